@@ -0,0 +1,402 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/dwetterau/glider/local"
+	"github.com/dwetterau/glider/local/calendar"
+	"github.com/dwetterau/glider/local/config"
+	"github.com/dwetterau/glider/local/control"
+	"github.com/dwetterau/glider/local/task"
+	"github.com/dwetterau/glider/local/track"
+)
+
+type command struct {
+	usage       string
+	description string
+	// Defines the command's own flags, if it has any, next to the ones from optionFlags.
+	flags func(flags *flag.FlagSet)
+	run   func(options local.Options, args []string) error
+}
+
+var commands = map[string]command{
+	"run": {
+		description: "Watch the focused window and annoy as needed",
+		run: func(options local.Options, args []string) error {
+			return local.Run(options)
+		},
+	},
+	"status": {
+		description: "Show how full each rule's bucket is",
+		run: func(options local.Options, args []string) error {
+			response, err := control.Send(options.SocketPath, control.Request{Command: control.CommandStatus})
+			if err != nil {
+				return err
+			}
+			return local.WriteStatus(os.Stdout, response.Status)
+		},
+	},
+	"report": {
+		usage:       "[today | yesterday]",
+		description: "Show where the time went, use -from and -to for other days",
+		flags:       reportFlags,
+		run:         report,
+	},
+	"pause": {
+		description: "Stop filling buckets and annoying until resumed",
+		run:         send(control.CommandPause, "Paused."),
+	},
+	"resume": {
+		description: "Start filling buckets and annoying again",
+		run:         send(control.CommandResume, "Resumed."),
+	},
+	"snooze": {
+		usage:       "<rule> <duration>",
+		description: "Stop a rule from annoying for a while, e.g. snooze slack 30m",
+		run: func(options local.Options, args []string) error {
+			if len(args) != 2 {
+				return errors.New("snooze needs a rule and a duration, e.g. snooze slack 30m")
+			}
+			return send(control.CommandSnooze, fmt.Sprintf("Snoozed %s for %s.", args[0], args[1]))(options, args)
+		},
+	},
+	"reset": {
+		usage:       "<rule>",
+		description: "Empty a rule's bucket",
+		run: func(options local.Options, args []string) error {
+			if len(args) != 1 {
+				return errors.New("reset needs the name of a rule")
+			}
+			return send(control.CommandReset, fmt.Sprintf("Emptied the %s bucket.", args[0]))(options, args)
+		},
+	},
+	"focus": {
+		usage:       "<duration> | stop",
+		description: "Start a focus session followed by a break, e.g. focus 50m",
+		flags:       focusFlags,
+		run:         focus,
+	},
+	"tune": {
+		description: "Suggest bucket sizes and drain factors from the tracking log",
+		flags:       tuneFlags,
+		run:         tune,
+	},
+	"calendar-review": {
+		description: "Rank recurring meetings by how worth dropping or shortening they are",
+		flags:       calendarReviewFlags,
+		run:         calendarReview,
+	},
+	"next": {
+		description: "Show the highest priority task that fits the time of day",
+		run: func(options local.Options, args []string) error {
+			c, err := config.LoadOrDefault(options.ConfigPath)
+			if err != nil {
+				return err
+			}
+			if c.Tasks.TodoTxt == "" && c.Tasks.Taskwarrior == "" {
+				return errors.New("there are no tasks to pick from, set todo_txt or taskwarrior under [tasks]")
+			}
+			tasks, err := task.Load(c.Tasks)
+			if err != nil {
+				return err
+			}
+			next, ok := c.Tasks.Next(tasks, time.Now())
+			if !ok {
+				fmt.Println("Nothing to do right now.")
+				return nil
+			}
+			fmt.Println(next)
+			return nil
+		},
+	},
+	"check-config": {
+		description: "Validate the config file",
+		run: func(options local.Options, args []string) error {
+			// The same verdict as run, which only falls back to the defaults at the default path.
+			c, err := config.LoadOrDefault(options.ConfigPath)
+			if err != nil {
+				return err
+			}
+			if options.ConfigPath == config.DefaultPath() {
+				if _, err := os.Stat(options.ConfigPath); os.IsNotExist(err) {
+					fmt.Printf("There's no config at %s, the default rules will be used\n", options.ConfigPath)
+					return nil
+				}
+			}
+			fmt.Printf("%s is valid and defines %d rules\n", options.ConfigPath, len(c.Rules))
+			return nil
+		},
+	},
+}
+
+// Sends a command to the running daemon. Commands that take a rule and a duration get
+// them from the first two arguments.
+func send(command, success string) func(local.Options, []string) error {
+	return func(options local.Options, args []string) error {
+		request := control.Request{Command: command}
+		if len(args) > 0 {
+			request.Rule = args[0]
+		}
+		if len(args) > 1 {
+			request.Duration = args[1]
+		}
+		_, err := control.Send(options.SocketPath, request)
+		if err == nil {
+			fmt.Println(success)
+		}
+		return err
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	name := os.Args[1]
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n", name)
+		usage()
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: glider %s [flags] %s\n\n%s.\n\n", name, cmd.usage, cmd.description)
+		flags.PrintDefaults()
+	}
+	options := optionFlags(flags)
+	if cmd.flags != nil {
+		cmd.flags(flags)
+	}
+	flags.Parse(os.Args[2:])
+
+	err := cmd.run(*options, flags.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Every command takes the same flags so they all agree on where the daemon keeps things.
+func optionFlags(flags *flag.FlagSet) *local.Options {
+	options := &local.Options{}
+	*options = local.DefaultOptions()
+	flags.StringVar(&options.ConfigPath, "config", options.ConfigPath, "The path to the rule config file")
+	flags.DurationVar(&options.SampleRate, "sample-rate", options.SampleRate, "How often to check the focused window")
+	flags.StringVar(
+		&options.Scanner, "scanner", options.Scanner,
+		"The window scanner backend to use (auto, x11, xdotool, sway or hyprland)",
+	)
+	flags.StringVar(
+		&options.IdleSource, "idle", options.IdleSource,
+		"Where to read the idle time from (auto, xscreensaver, logind or none)",
+	)
+	flags.DurationVar(
+		&options.IdleThreshold, "idle-threshold", options.IdleThreshold,
+		"How long without input before time stops counting towards the focused application",
+	)
+	flags.StringVar(&options.StatePath, "state", options.StatePath, "Where to keep bucket levels across restarts")
+	flags.StringVar(&options.TrackPath, "track", options.TrackPath, "The SQLite file to log focused windows to")
+	flags.StringVar(&options.SocketPath, "socket", options.SocketPath, "The control socket of the daemon")
+	flags.StringVar(
+		&options.RecordPath, "record", options.RecordPath,
+		"A file to append every window the scanner finds to, for replaying in tests",
+	)
+	return options
+}
+
+var reportOptions struct {
+	from, to string
+}
+
+func reportFlags(flags *flag.FlagSet) {
+	flags.StringVar(&reportOptions.from, "from", "", "The first day to report on (YYYY-MM-DD)")
+	flags.StringVar(&reportOptions.to, "to", "", "The last day to report on (YYYY-MM-DD), defaults to -from")
+}
+
+func report(options local.Options, args []string) error {
+	day := ""
+	if len(args) > 0 {
+		day = args[0]
+	}
+	start, end, err := local.ReportRange(time.Now(), day, reportOptions.from, reportOptions.to)
+	if err != nil {
+		return err
+	}
+	tracker, err := track.NewSQLite(options.TrackPath)
+	if err != nil {
+		return fmt.Errorf("unable to open the tracking log: %v", err)
+	}
+	// The config only adds to the report, so a broken one shouldn't get in the way.
+	c, err := config.LoadOrDefault(options.ConfigPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Using the default config, leaving out meetings: %v\n", err)
+		c = config.Default()
+	}
+	return local.WriteReport(os.Stdout, tracker, c, meetings(c, start, end), start, end)
+}
+
+// Returns the meetings in [from, to) from the calendars in the config, if there are any.
+// Problems are only warned about, since they shouldn't get in the way of the rest of the
+// report.
+func meetings(c *config.Config, from, to time.Time) []calendar.Occurrence {
+	if len(c.Calendar.Paths) == 0 {
+		return nil
+	}
+	events, err := calendar.Load(c.Calendar.Paths)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Some of the calendar couldn't be read: %v\n", err)
+	}
+	return calendar.Meetings(events, from, to)
+}
+
+var focusOptions struct {
+	breakLength string
+}
+
+func focusFlags(flags *flag.FlagSet) {
+	flags.StringVar(
+		&focusOptions.breakLength, "break", "",
+		"How long the break after the session is, defaults to the configured break",
+	)
+}
+
+func focus(options local.Options, args []string) error {
+	if len(args) != 1 {
+		return errors.New("focus needs the length of the session, e.g. focus 50m, or stop")
+	}
+	if args[0] == "stop" {
+		_, err := control.Send(options.SocketPath, control.Request{Command: control.CommandStopFocus})
+		if err == nil {
+			fmt.Println("Stopped the focus session.")
+		}
+		return err
+	}
+	_, err := control.Send(options.SocketPath, control.Request{
+		Command:  control.CommandFocus,
+		Duration: args[0],
+		Break:    focusOptions.breakLength,
+	})
+	if err == nil {
+		fmt.Printf("Focusing for %s.\n", args[0])
+	}
+	return err
+}
+
+var tuneOptions struct {
+	days  int
+	write bool
+}
+
+func tuneFlags(flags *flag.FlagSet) {
+	flags.IntVar(&tuneOptions.days, "days", 28, "How many days of history to learn from")
+	flags.BoolVar(&tuneOptions.write, "write", false, "Save the suggestions to the config file")
+}
+
+func tune(options local.Options, args []string) error {
+	days := tuneOptions.days
+	if days <= 0 {
+		return errors.New("-days must be positive")
+	}
+	c, err := config.LoadOrDefault(options.ConfigPath)
+	if err != nil {
+		return err
+	}
+	tracker, err := track.NewSQLite(options.TrackPath)
+	if err != nil {
+		return fmt.Errorf("unable to open the tracking log: %v", err)
+	}
+	now := time.Now()
+	suggestions, err := local.Tune(tracker, c.Rules, now.AddDate(0, 0, -days), now)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Suggestions from the last %d days:\n", days)
+	if err := local.WriteTuning(os.Stdout, c.Rules, suggestions); err != nil {
+		return err
+	}
+	updates := make(map[string]config.RuleUpdate)
+	for _, suggestion := range suggestions {
+		if suggestion.Skipped == "" {
+			updates[suggestion.Rule] = config.RuleUpdate{
+				BucketSize: suggestion.BucketSize, DrainFactor: suggestion.DrainFactor,
+			}
+		}
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	if !tuneOptions.write {
+		fmt.Println("\nRun glider tune -write to save them to the config file.")
+		return nil
+	}
+	if _, err := os.Stat(options.ConfigPath); os.IsNotExist(err) {
+		return fmt.Errorf("there's no config file at %s to save them to", options.ConfigPath)
+	}
+	if err := config.UpdateRules(options.ConfigPath, updates); err != nil {
+		return err
+	}
+	fmt.Printf("\nSaved %d suggestions to %s.\n", len(updates), options.ConfigPath)
+	return nil
+}
+
+var calendarReviewOptions struct {
+	from string
+}
+
+func calendarReviewFlags(flags *flag.FlagSet) {
+	flags.StringVar(
+		&calendarReviewOptions.from, "from", "",
+		"The first day of the week to review (YYYY-MM-DD), defaults to a week ago",
+	)
+}
+
+func calendarReview(options local.Options, args []string) error {
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), now.Day()-7, 0, 0, 0, 0, now.Location())
+	if calendarReviewOptions.from != "" {
+		var err error
+		start, err = time.ParseInLocation("2006-01-02", calendarReviewOptions.from, now.Location())
+		if err != nil {
+			return fmt.Errorf("invalid -from date: %v", err)
+		}
+	}
+	end := start.AddDate(0, 0, 7)
+	c, err := config.LoadOrDefault(options.ConfigPath)
+	if err != nil {
+		return err
+	}
+	tracker, err := track.NewSQLite(options.TrackPath)
+	if err != nil {
+		return fmt.Errorf("unable to open the tracking log: %v", err)
+	}
+	meetings, err := local.ReviewMeetings(c, tracker, start, end)
+	if err != nil {
+		return err
+	}
+	fmt.Printf(
+		"Recurring meetings from %s to %s, most worth dropping first:\n",
+		start.Format("2006-01-02"), end.AddDate(0, 0, -1).Format("2006-01-02"),
+	)
+	return local.WriteMeetingReview(os.Stdout, meetings)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: glider <command> [flags]\n\nCommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", name, commands[name].description)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun glider <command> -h to see the flags of a command.")
+}
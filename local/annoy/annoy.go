@@ -1,109 +1,474 @@
 package annoy
 
 import (
-	"regexp"
+	"fmt"
+	"sync"
 	"time"
 
-	"github.com/dwetterau/glider/local/tool"
+	"github.com/dwetterau/glider/local/clock"
+	"github.com/dwetterau/glider/local/enforce"
+	"github.com/dwetterau/glider/local/notify"
 	"github.com/dwetterau/glider/local/xscan"
 )
 
-type annoyingApplication int
+// Every notification offers to snooze the rule for this long.
+const snoozeAction = "Snooze 15 minutes"
+const snoozeActionDuration = 15 * time.Minute
 
-const (
-	Unknown annoyingApplication = iota
-	Slack
-	GMail
-)
+type Annoyer interface {
+	MaybeAnnoy(window xscan.Window, duration time.Duration) bool
+	Clear(window xscan.Window)
 
-var thresholds = map[annoyingApplication]threshold{
-	Slack: {3 * time.Minute, .5},
-	GMail: {5 * time.Minute, 10},
-}
+	// Returns the name of the rule the window counts towards, or "" if there is none.
+	// Rules are skipped while they're outside of their schedule.
+	Match(window xscan.Window) string
+
+	// Swaps in a new set of rules and notifiers. Buckets, budget usage and escalations
+	// carry over for rules whose identity (their name and what they match) didn't change,
+	// though escalations start over if the steps did. Rules that are swapped out keep
+	// theirs until they're swapped back in or forgotten.
+	SetRules(rules []Rule, notifiers map[string]notify.Notifier)
+
+	// Drops the buckets and budget usage kept for swapped out rules, except for the
+	// given ones.
+	Forget(keep []Rule)
+
+	// Returns how full each rule's bucket is, keyed by rule name. Swapped out rules are
+	// included, unless an active rule has the same name.
+	Buckets() map[string]time.Duration
+
+	// Refills buckets from an earlier Buckets call, then drains them for the time that
+	// has passed since. Buckets of rules that no longer exist are ignored.
+	Restore(buckets map[string]time.Duration, elapsed time.Duration)
+
+	// Returns how much of its daily budget each rule that has one used, keyed by rule
+	// name. Like with Buckets, swapped out rules are included.
+	Budgets() map[string]BudgetUsage
+
+	// Picks up budget usage from an earlier Budgets call. Usage from an earlier budget
+	// day is dropped.
+	RestoreBudgets(budgets map[string]BudgetUsage)
+
+	// Returns the rules in the order they're checked.
+	Rules() []Rule
+
+	// Empties the named rule's bucket. Returns false if there's no such rule.
+	Reset(rule string) bool
+
+	// Keeps the named rule from annoying until the given time, while its bucket keeps
+	// filling up. Returns false if there's no such rule.
+	Snooze(rule string, until time.Time) bool
 
-type threshold struct {
-	// The annoyer will annoy if an application accumulates this amount of duration.
-	bucketSize time.Duration
+	// Returns when the named rule's snooze ends, or the zero time if it isn't snoozed.
+	SnoozedUntil(rule string) time.Time
 
-	// A value in [0, inf) that is multiplied by the time elapsed and subtracted from the
-	// accumulated bucket when the application is not active.
-	drainFactor float64
+	// Returns the most recent escalation steps the named rule fired, oldest first.
+	History(rule string) []Escalation
+
+	// Sets what nags suggest doing instead, e.g. the next task on a todo list. The
+	// function returns "" when there's nothing to suggest, and a nil one suggests nothing.
+	SetNextTask(next func(now time.Time) string)
 }
 
-type Annoyer interface {
-	MaybeAnnoy(window xscan.Window, duration time.Duration) bool
-	Clear(window xscan.Window)
+// Rules are checked in order and a window counts towards the first one that matches.
+// Each rule's notifications are sent to the notifiers named in the rule, and the actions
+// of its escalation steps are taken by the enforcer. A nil enforcer takes no actions.
+func NewAnnoyer(rules []Rule, notifiers map[string]notify.Notifier, enforcer *enforce.Enforcer) Annoyer {
+	return NewAnnoyerWithClock(rules, notifiers, enforcer, clock.Real{})
 }
 
-func NewAnnoyer() Annoyer {
+// Like NewAnnoyer, but schedules, snoozes and escalations go by the given clock.
+func NewAnnoyerWithClock(
+	rules []Rule, notifiers map[string]notify.Notifier, enforcer *enforce.Enforcer, clock clock.Clock,
+) Annoyer {
+	buckets := make(map[string]time.Duration, len(rules))
+	for _, rule := range rules {
+		buckets[rule.Name] = 0
+	}
 	return &annoyerImpl{
-		buckets: map[annoyingApplication]time.Duration{
-			Unknown: 0,
-			Slack:   0,
-			GMail:   0,
-		},
+		rules:       rules,
+		notifiers:   notifiers,
+		enforcer:    enforcer,
+		clock:       clock,
+		buckets:     buckets,
+		snoozes:     make(map[string]time.Time),
+		escalations: make(map[string]*escalation),
+		budgets:     make(map[string]*BudgetUsage),
+		swappedOut:  make(map[string]swappedOut),
 	}
 }
 
+// The state of a rule that isn't in the current set, e.g. while a meeting profile's
+// rules take the place of the usual ones.
+type swappedOut struct {
+	rule   Rule
+	bucket time.Duration
+	// Nil if the rule has no budget or didn't use any of it.
+	budget *BudgetUsage
+	// Nil if the rule's bucket never got full.
+	escalation *escalation
+}
+
+// Snoozing from a notification happens on another goroutine, so everything is guarded by
+// the lock.
 type annoyerImpl struct {
-	buckets map[annoyingApplication]time.Duration
+	lock        sync.Mutex
+	rules       []Rule
+	notifiers   map[string]notify.Notifier
+	enforcer    *enforce.Enforcer
+	clock       clock.Clock
+	buckets     map[string]time.Duration
+	snoozes     map[string]time.Time
+	escalations map[string]*escalation
+	budgets     map[string]*BudgetUsage
+	// Keyed by rule identity.
+	swappedOut map[string]swappedOut
+	nextTask   func(now time.Time) string
 }
 
 func (a *annoyerImpl) MaybeAnnoy(window xscan.Window, duration time.Duration) bool {
-	application := classify(window)
-	a.drainBuckets(application, duration)
-	if application == Unknown {
+	if duration <= 0 {
+		// Nothing to charge, and negative time would drain buckets backwards.
+		return false
+	}
+	a.lock.Lock()
+	now := a.clock.Now()
+	rule := a.classify(window, now)
+	if rule == nil {
+		// Idle time and unknown windows drain every bucket.
+		a.drainBuckets("", duration)
+		a.lock.Unlock()
 		return false
 	}
+	a.drainBuckets(rule.Name, duration)
+	// While snoozed, buckets and budgets keep filling up but nothing fires.
+	snoozed := !a.snoozedUntil(rule.Name, now).IsZero()
 
-	a.buckets[application] += duration
-	if a.buckets[application] > thresholds[application].bucketSize {
-		tool.Run("notify-send", "Glider", message(application))
-		return true
+	step := -1
+	if rule.BucketSize > 0 {
+		a.buckets[rule.Name] += duration
+		step = a.escalate(*rule, now, window, snoozed)
+	}
+	var notice *budgetNotice
+	if rule.Budget.Daily > 0 {
+		usage := a.budgetUsage(*rule, now)
+		usage.Used += duration
+		if !snoozed {
+			notice = usage.due(*rule)
+		}
+	}
+	notifiers := a.notifiers
+	nextTask := a.nextTask
+	a.lock.Unlock()
+
+	// Notifiers can be slow, so don't hold up snoozing while they run. Neither can
+	// reading the task list.
+	suggestion := ""
+	if nextTask != nil && (notice != nil || step != -1) {
+		if task := nextTask(now); task != "" {
+			suggestion = "\n\nNext up: " + task
+		}
+	}
+	if notice != nil {
+		a.notify(*rule, notice.message+suggestion, notice.urgency, notifiers)
+	}
+	if step != -1 {
+		s := rule.steps()[step]
+		a.notify(*rule, rule.message(s)+suggestion, s.Urgency, notifiers)
+		a.act(s, window)
+	}
+	return step != -1 || notice != nil
+}
+
+// Returns the escalation step that fires now that the rule's bucket grew, or -1 if none
+// does.
+func (a *annoyerImpl) escalate(rule Rule, now time.Time, window xscan.Window, snoozed bool) int {
+	e := a.escalation(rule.Name)
+	overflow := a.buckets[rule.Name] - rule.BucketSize
+	if overflow <= 0 {
+		e.reset()
+		return -1
+	}
+	if snoozed {
+		// Hold off on escalating until the snooze is over.
+		return -1
+	}
+	step := e.due(rule, overflow)
+	if step != -1 {
+		e.fired(now, step, window)
+	}
+	return step
+}
+
+// Takes the step's action on the offending window.
+func (a *annoyerImpl) act(step Step, window xscan.Window) {
+	if step.Action == ActionNone || a.enforcer == nil {
+		return
+	}
+	err := a.enforcer.Do(step.Action, step.Target, step.DryRun, window)
+	if err == enforce.ErrAllowlisted {
+		fmt.Printf("Not taking action on %v, it's allowlisted\n", window)
+	} else if err != nil {
+		fmt.Printf("Unable to %s %v: %v\n", step.Action, window, err)
+	}
+}
+
+func (a *annoyerImpl) escalation(rule string) *escalation {
+	e, ok := a.escalations[rule]
+	if !ok {
+		e = &escalation{}
+		a.escalations[rule] = e
+	}
+	return e
+}
+
+// Returns the rule's budget usage for the budget day that now is in.
+func (a *annoyerImpl) budgetUsage(rule Rule, now time.Time) *BudgetUsage {
+	usage, ok := a.budgets[rule.Name]
+	if !ok {
+		usage = &BudgetUsage{}
+		a.budgets[rule.Name] = usage
+	}
+	usage.roll(rule.Budget, now)
+	return usage
+}
+
+func (a *annoyerImpl) notify(rule Rule, body string, urgency notify.Urgency, notifiers map[string]notify.Notifier) {
+	notification := notify.Notification{
+		Title:   "Glider",
+		Body:    body,
+		Urgency: urgency,
+		Actions: []string{snoozeAction},
+		OnAction: func(action string) {
+			if action == snoozeAction {
+				a.Snooze(rule.Name, a.clock.Now().Add(snoozeActionDuration))
+			}
+		},
+	}
+	for _, name := range rule.notifierNames() {
+		n, ok := notifiers[name]
+		if !ok {
+			fmt.Printf("Rule %s uses the unknown notifier %s\n", rule.Name, name)
+			continue
+		}
+		if err := n.Notify(notification); err != nil {
+			fmt.Printf("Unable to notify with %s: %v\n", name, err)
+		}
 	}
-	return false
 }
 
-func (a *annoyerImpl) drainBuckets(curApplication annoyingApplication, duration time.Duration) {
-	for application, bucket := range a.buckets {
-		if application == curApplication {
+func (a *annoyerImpl) drainBuckets(curRule string, duration time.Duration) {
+	for _, rule := range a.rules {
+		if rule.Name == curRule {
 			continue
 		}
-		a.buckets[application] = bucket - time.Duration(thresholds[application].drainFactor*float64(duration))
-		if a.buckets[application] < 0 {
-			a.buckets[application] = 0
+		a.buckets[rule.Name] -= time.Duration(rule.DrainFactor * float64(duration))
+		if a.buckets[rule.Name] < 0 {
+			a.buckets[rule.Name] = 0
 		}
 	}
 }
 
 func (a *annoyerImpl) Clear(window xscan.Window) {
-	a.buckets[classify(window)] = 0
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if rule := a.classify(window, a.clock.Now()); rule != nil {
+		a.buckets[rule.Name] = 0
+	}
 }
 
-var (
-	slackRegex = regexp.MustCompile(` \| .* Slack\b`)
-	gmailRegex = regexp.MustCompile(`\S+@\S+\.\S+.+G?[mM]ail -`)
-)
+func (a *annoyerImpl) Match(window xscan.Window) string {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if rule := a.classify(window, a.clock.Now()); rule != nil {
+		return rule.Name
+	}
+	return ""
+}
+
+func (a *annoyerImpl) SetRules(rules []Rule, notifiers map[string]notify.Notifier) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	oldRules := make(map[string]Rule, len(a.rules))
+	for _, rule := range a.rules {
+		oldRules[rule.Name] = rule
+	}
+	buckets := make(map[string]time.Duration, len(rules))
+	budgets := make(map[string]*BudgetUsage, len(rules))
+	escalations := make(map[string]*escalation, len(rules))
+	identities := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		identity := rule.identity()
+		identities[identity] = true
+		buckets[rule.Name] = 0
+		if oldRule, ok := oldRules[rule.Name]; ok && oldRule.identity() == identity {
+			buckets[rule.Name] = a.buckets[rule.Name]
+			if usage, ok := a.budgets[rule.Name]; ok {
+				budgets[rule.Name] = usage
+			}
+			if e, ok := a.escalations[rule.Name]; ok {
+				escalations[rule.Name] = e.carryOver(oldRule, rule)
+			}
+		} else if out, ok := a.swappedOut[identity]; ok {
+			buckets[rule.Name] = out.bucket
+			if out.budget != nil {
+				budgets[rule.Name] = out.budget
+			}
+			if out.escalation != nil {
+				escalations[rule.Name] = out.escalation.carryOver(out.rule, rule)
+			}
+		}
+		delete(a.swappedOut, identity)
+	}
+	for _, rule := range a.rules {
+		if identity := rule.identity(); !identities[identity] {
+			a.swappedOut[identity] = swappedOut{
+				rule:       rule,
+				bucket:     a.buckets[rule.Name],
+				budget:     a.budgets[rule.Name],
+				escalation: a.escalations[rule.Name],
+			}
+		}
+	}
+	a.rules = rules
+	a.notifiers = notifiers
+	a.buckets = buckets
+	a.budgets = budgets
+	a.escalations = escalations
+}
 
-func classify(window xscan.Window) annoyingApplication {
-	foundSlack := slackRegex.Find([]byte(window.Title))
-	if foundSlack != nil {
-		return Slack
+func (a *annoyerImpl) Forget(keep []Rule) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	kept := make(map[string]bool, len(keep))
+	for _, rule := range keep {
+		kept[rule.identity()] = true
+	}
+	for identity := range a.swappedOut {
+		if !kept[identity] {
+			delete(a.swappedOut, identity)
+		}
 	}
-	foundGmail := gmailRegex.Find([]byte(window.Title))
-	if foundGmail != nil {
-		return GMail
+}
+
+func (a *annoyerImpl) Buckets() map[string]time.Duration {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	buckets := make(map[string]time.Duration, len(a.buckets)+len(a.swappedOut))
+	for _, out := range a.swappedOut {
+		buckets[out.rule.Name] = out.bucket
+	}
+	for name, bucket := range a.buckets {
+		buckets[name] = bucket
+	}
+	return buckets
+}
+
+func (a *annoyerImpl) Restore(buckets map[string]time.Duration, elapsed time.Duration) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	for name, bucket := range buckets {
+		if _, ok := a.buckets[name]; ok {
+			a.buckets[name] = bucket
+		}
+	}
+	a.drainBuckets("", elapsed)
+}
+
+func (a *annoyerImpl) Budgets() map[string]BudgetUsage {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	now := a.clock.Now()
+	budgets := make(map[string]BudgetUsage)
+	for _, out := range a.swappedOut {
+		if out.budget != nil && out.rule.Budget.Daily > 0 {
+			out.budget.roll(out.rule.Budget, now)
+			budgets[out.rule.Name] = *out.budget
+		}
+	}
+	for _, rule := range a.rules {
+		if rule.Budget.Daily > 0 {
+			budgets[rule.Name] = *a.budgetUsage(rule, now)
+		}
 	}
-	return Unknown
+	return budgets
 }
 
-func message(application annoyingApplication) string {
-	switch application {
-	case Slack:
-		return "Stop reading Slack."
-	case GMail:
-		return "Read your email faster or not at all."
-	default:
-		return "Shouldn't you be doing something else?"
+func (a *annoyerImpl) RestoreBudgets(budgets map[string]BudgetUsage) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	for _, rule := range a.rules {
+		usage, ok := budgets[rule.Name]
+		if ok && rule.Budget.Daily > 0 {
+			a.budgets[rule.Name] = &usage
+		}
+	}
+}
+
+func (a *annoyerImpl) Rules() []Rule {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.rules
+}
+
+func (a *annoyerImpl) Reset(rule string) bool {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if _, ok := a.buckets[rule]; !ok {
+		return false
+	}
+	a.buckets[rule] = 0
+	return true
+}
+
+func (a *annoyerImpl) Snooze(rule string, until time.Time) bool {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if _, ok := a.buckets[rule]; !ok {
+		return false
+	}
+	a.snoozes[rule] = until
+	return true
+}
+
+func (a *annoyerImpl) SnoozedUntil(rule string) time.Time {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.snoozedUntil(rule, a.clock.Now())
+}
+
+func (a *annoyerImpl) snoozedUntil(rule string, now time.Time) time.Time {
+	until, ok := a.snoozes[rule]
+	if ok && !now.Before(until) {
+		delete(a.snoozes, rule)
+		return time.Time{}
+	}
+	return until
+}
+
+func (a *annoyerImpl) History(rule string) []Escalation {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if e, ok := a.escalations[rule]; ok {
+		return append([]Escalation(nil), e.history...)
+	}
+	return nil
+}
+
+func (a *annoyerImpl) SetNextTask(next func(now time.Time) string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.nextTask = next
+}
+
+func (a *annoyerImpl) classify(window xscan.Window, now time.Time) *Rule {
+	if window == xscan.IdleWindow {
+		return nil
+	}
+	for i := range a.rules {
+		if a.rules[i].Active(now) && a.rules[i].Matches(window) {
+			return &a.rules[i]
+		}
 	}
+	return nil
 }
@@ -0,0 +1,202 @@
+package annoy
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dwetterau/glider/local/clock"
+	"github.com/dwetterau/glider/local/notify"
+	"github.com/dwetterau/glider/local/schedule"
+	"github.com/dwetterau/glider/local/xscan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule(t *testing.T) {
+	deepWork, err := schedule.Parse([]string{"Mon-Fri 09:00-12:00"})
+	require.NoError(t, err)
+	rules := []Rule{{
+		Name:            "slack",
+		ApplicationName: "Slack",
+		BucketSize:      time.Minute,
+		DrainFactor:     1,
+		Schedule:        deepWork,
+	}}
+	recorder := &notify.Recorder{}
+	// 2018-09-03 was a Monday.
+	c := clock.NewFake(time.Date(2018, time.September, 3, 8, 0, 0, 0, time.Local))
+	a := NewAnnoyerWithClock(rules, map[string]notify.Notifier{DefaultNotifier: recorder}, nil, c)
+	slack := xscan.Window{ApplicationName: "Slack"}
+
+	// Before the schedule starts Slack doesn't count at all.
+	assert.Equal(t, "", a.Match(slack))
+	assert.False(t, a.MaybeAnnoy(slack, 2*time.Minute))
+	assert.Equal(t, time.Duration(0), a.Buckets()["slack"])
+
+	c.Set(time.Date(2018, time.September, 3, 9, 0, 0, 0, time.Local))
+	assert.Equal(t, "slack", a.Match(slack))
+	assert.False(t, a.MaybeAnnoy(slack, 30*time.Second))
+	assert.True(t, a.MaybeAnnoy(slack, time.Minute))
+	assert.Len(t, recorder.Notifications(), 1)
+
+	// Nor does it on the weekend.
+	c.Set(time.Date(2018, time.September, 8, 10, 0, 0, 0, time.Local))
+	a.Reset("slack")
+	assert.False(t, a.MaybeAnnoy(slack, 10*time.Minute))
+	assert.Equal(t, time.Duration(0), a.Buckets()["slack"])
+	assert.Len(t, recorder.Notifications(), 1)
+}
+
+func TestBudget(t *testing.T) {
+	rules := []Rule{{
+		Name:            "email",
+		ApplicationName: "Thunderbird",
+		Budget:          Budget{Daily: 45 * time.Minute, Reset: 4 * time.Hour},
+	}}
+	recorder := &notify.Recorder{}
+	c := clock.NewFake(time.Date(2018, time.September, 3, 9, 0, 0, 0, time.Local))
+	a := NewAnnoyerWithClock(rules, map[string]notify.Notifier{DefaultNotifier: recorder}, nil, c)
+	email := xscan.Window{ApplicationName: "Thunderbird"}
+	other := xscan.Window{ApplicationName: "Code"}
+
+	// Short visits add up, no matter how much time passes in between.
+	var bodies []string
+	for i := 0; i < 25; i++ {
+		if a.MaybeAnnoy(email, 2*time.Minute) {
+			notifications := recorder.Notifications()
+			bodies = append(bodies, notifications[len(notifications)-1].Body)
+		}
+		a.MaybeAnnoy(other, 20*time.Minute)
+		c.Advance(10 * time.Minute)
+	}
+	assert.Equal(t, []string{
+		"You've used 50% of your 45m0s email budget for today.",
+		"You've used 80% of your 45m0s email budget for today.",
+		"Your 45m0s email budget for today is used up.",
+	}, bodies)
+	assert.Equal(t, notify.Critical, recorder.Notifications()[2].Urgency)
+	assert.Equal(t, 50*time.Minute, a.Budgets()["email"].Used)
+	// There's no bucket to fill.
+	assert.Equal(t, time.Duration(0), a.Buckets()["email"])
+
+	// The budget starts over at 4am.
+	c.Set(time.Date(2018, time.September, 4, 3, 59, 0, 0, time.Local))
+	assert.Equal(t, 50*time.Minute, a.Budgets()["email"].Used)
+	c.Set(time.Date(2018, time.September, 4, 4, 0, 0, 0, time.Local))
+	assert.Equal(t, time.Duration(0), a.Budgets()["email"].Used)
+
+	// Usage survives a restart within the same day, but not into the next one.
+	saved := BudgetUsage{
+		Day:  time.Date(2018, time.September, 4, 4, 0, 0, 0, time.Local),
+		Used: 30 * time.Minute, Warned: 1,
+	}
+	a.RestoreBudgets(map[string]BudgetUsage{"email": saved})
+	assert.Equal(t, saved, a.Budgets()["email"])
+	c.Set(time.Date(2018, time.September, 5, 4, 0, 0, 0, time.Local))
+	assert.Equal(t, time.Duration(0), a.Budgets()["email"].Used)
+}
+
+func TestNextTask(t *testing.T) {
+	rules := []Rule{{Name: "slack", ApplicationName: "Slack", BucketSize: time.Minute, DrainFactor: 1}}
+	recorder := &notify.Recorder{}
+	c := clock.NewFake(time.Date(2018, time.September, 3, 9, 0, 0, 0, time.Local))
+	a := NewAnnoyerWithClock(rules, map[string]notify.Notifier{DefaultNotifier: recorder}, nil, c)
+	slack := xscan.Window{ApplicationName: "Slack"}
+	var asked []time.Time
+	next := "(A) Write the report"
+	a.SetNextTask(func(now time.Time) string {
+		asked = append(asked, now)
+		return next
+	})
+
+	// The task list is only read when there's a nag to send.
+	assert.False(t, a.MaybeAnnoy(slack, 30*time.Second))
+	assert.Empty(t, asked)
+	require.True(t, a.MaybeAnnoy(slack, time.Minute))
+	assert.Equal(t, []time.Time{c.Now()}, asked)
+	require.Len(t, recorder.Notifications(), 1)
+	assert.True(t, strings.HasSuffix(recorder.Notifications()[0].Body, "\n\nNext up: (A) Write the report"))
+
+	// Nothing is added when there's nothing to do.
+	next = ""
+	a.Reset("slack")
+	assert.False(t, a.MaybeAnnoy(slack, 30*time.Second))
+	require.True(t, a.MaybeAnnoy(slack, time.Minute))
+	require.Len(t, recorder.Notifications(), 2)
+	assert.NotContains(t, recorder.Notifications()[1].Body, "Next up")
+}
+
+func TestNonPositiveDuration(t *testing.T) {
+	rules := DefaultRules()
+	a := NewAnnoyerWithClock(rules, map[string]notify.Notifier{DefaultNotifier: &notify.Recorder{}}, nil, clock.NewFake(time.Now()))
+	slack := xscan.Window{ApplicationName: "Slack", Title: "general | Team Slack"}
+	gmail := xscan.Window{ApplicationName: "firefox", Title: "Inbox - me@example.com - Gmail - Mozilla Firefox"}
+	a.MaybeAnnoy(gmail, time.Minute)
+
+	// Late events must neither drain the other buckets backwards nor empty this one.
+	assert.False(t, a.MaybeAnnoy(slack, -2*time.Second))
+	assert.False(t, a.MaybeAnnoy(slack, 0))
+	assert.Equal(t, map[string]time.Duration{"slack": 0, "gmail": time.Minute}, a.Buckets())
+}
+
+func TestSwapRules(t *testing.T) {
+	slack := Rule{Name: "slack", ApplicationName: "Slack", BucketSize: 3 * time.Minute, DrainFactor: 1}
+	mail := Rule{Name: "mail", ApplicationName: "Thunderbird", BucketSize: time.Hour, Budget: Budget{Daily: time.Hour}}
+	c := clock.NewFake(time.Date(2018, time.September, 3, 9, 0, 0, 0, time.Local))
+	notifiers := map[string]notify.Notifier{DefaultNotifier: &notify.Recorder{}}
+	a := NewAnnoyerWithClock([]Rule{slack, mail}, notifiers, nil, c)
+	a.MaybeAnnoy(xscan.Window{ApplicationName: "Thunderbird"}, 20*time.Minute)
+
+	a.SetRules([]Rule{slack}, notifiers)
+	assert.Equal(t, map[string]time.Duration{"slack": 0, "mail": 20 * time.Minute}, a.Buckets())
+	assert.Equal(t, 20*time.Minute, a.Budgets()["mail"].Used)
+
+	// Swapping back in, even with a new bucket size, picks up where the rule left off.
+	bigger := mail
+	bigger.BucketSize = 2 * time.Hour
+	a.SetRules([]Rule{slack, bigger}, notifiers)
+	assert.Equal(t, 20*time.Minute, a.Buckets()["mail"])
+	assert.Equal(t, 20*time.Minute, a.Budgets()["mail"].Used)
+
+	a.SetRules([]Rule{slack}, notifiers)
+	a.Forget([]Rule{slack})
+	a.SetRules([]Rule{slack, mail}, notifiers)
+	assert.Equal(t, time.Duration(0), a.Buckets()["mail"])
+	assert.Equal(t, time.Duration(0), a.Budgets()["mail"].Used)
+}
+
+func TestReloadEscalation(t *testing.T) {
+	slack := Rule{
+		Name: "slack", ApplicationName: "Slack", BucketSize: 3 * time.Minute,
+		Escalation: []Step{{After: 0}, {After: 5 * time.Minute}, {After: 10 * time.Minute, Urgency: notify.Critical}},
+	}
+	window := xscan.Window{ApplicationName: "Slack"}
+	c := clock.NewFake(time.Date(2018, time.September, 3, 9, 0, 0, 0, time.Local))
+	notifiers := map[string]notify.Notifier{DefaultNotifier: &notify.Recorder{}}
+	a := NewAnnoyerWithClock([]Rule{slack}, notifiers, nil, c)
+	assert.False(t, a.MaybeAnnoy(window, 3*time.Minute))
+	assert.True(t, a.MaybeAnnoy(window, time.Second))
+	assert.True(t, a.MaybeAnnoy(window, 10*time.Minute))
+	assert.False(t, a.MaybeAnnoy(window, time.Minute))
+
+	// Unchanged steps carry on where they were.
+	a.SetRules([]Rule{slack}, notifiers)
+	assert.False(t, a.MaybeAnnoy(window, time.Minute))
+
+	// With fewer steps than have fired, the policy starts over instead of going silent.
+	shorter := slack
+	shorter.Escalation = []Step{{After: 0}, {After: 5 * time.Minute}}
+	shorter.Repeat = 2 * time.Minute
+	a.SetRules([]Rule{shorter}, notifiers)
+	assert.True(t, a.MaybeAnnoy(window, time.Second))
+	assert.False(t, a.MaybeAnnoy(window, time.Minute))
+	assert.True(t, a.MaybeAnnoy(window, time.Minute))
+	require.Len(t, a.History("slack"), 4)
+
+	// A different rule that reuses the name starts from scratch.
+	discord := shorter
+	discord.ApplicationName = "discord"
+	a.SetRules([]Rule{discord}, notifiers)
+	assert.Empty(t, a.History("slack"))
+}
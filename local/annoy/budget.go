@@ -0,0 +1,103 @@
+package annoy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dwetterau/glider/local/notify"
+)
+
+// A limit on how much time a rule's windows get each day in total, no matter how it's
+// spread out.
+type Budget struct {
+	// Zero means the rule has no budget.
+	Daily time.Duration
+
+	// The local time of day the budget starts over, as the time since midnight.
+	Reset time.Duration
+
+	// Percentages of the budget to send warnings at, in increasing order. Defaults to
+	// DefaultBudgetWarnings. A final notice is always sent once the budget is used up.
+	Warnings []float64
+}
+
+var DefaultBudgetWarnings = []float64{50, 80}
+
+// How much of a rule's budget has been used since it last started over.
+type BudgetUsage struct {
+	// When the budget last started over.
+	Day  time.Time     `json:"day"`
+	Used time.Duration `json:"used"`
+	// How many of the warnings have been sent.
+	Warned    int  `json:"warned"`
+	Exhausted bool `json:"exhausted"`
+}
+
+func (b Budget) warnings() []float64 {
+	if b.Warnings == nil {
+		return DefaultBudgetWarnings
+	}
+	return b.Warnings
+}
+
+// Returns when the budget day that now is in started.
+func (b Budget) dayStart(now time.Time) time.Time {
+	year, month, day := now.Date()
+	start := b.resetOn(year, month, day, now.Location())
+	if now.Before(start) {
+		start = b.resetOn(year, month, day-1, now.Location())
+	}
+	return start
+}
+
+// Reset is a time on the clock, which isn't Reset after midnight on the days daylight
+// saving time starts or ends.
+func (b Budget) resetOn(year int, month time.Month, day int, location *time.Location) time.Time {
+	hour := int(b.Reset / time.Hour)
+	minute := int(b.Reset % time.Hour / time.Minute)
+	return time.Date(year, month, day, hour, minute, 0, 0, location)
+}
+
+// A notification about a budget that is due.
+type budgetNotice struct {
+	message string
+	urgency notify.Urgency
+}
+
+// Starts the usage over if a new budget day began.
+func (u *BudgetUsage) roll(b Budget, now time.Time) {
+	if day := b.dayStart(now); !day.Equal(u.Day) {
+		*u = BudgetUsage{Day: day}
+	}
+}
+
+// Returns the notice that's due now, if any. When several warnings are due at once only
+// the last of them is sent, and the final notice replaces them all.
+func (u *BudgetUsage) due(rule Rule) *budgetNotice {
+	budget := rule.Budget
+	if u.Exhausted {
+		return nil
+	}
+	if u.Used >= budget.Daily {
+		u.Exhausted = true
+		return &budgetNotice{
+			message: fmt.Sprintf("Your %v %s budget for today is used up.", budget.Daily, rule.Name),
+			urgency: notify.Critical,
+		}
+	}
+	warnings := budget.warnings()
+	warning := -1
+	for u.Warned < len(warnings) && float64(u.Used) >= warnings[u.Warned]/100*float64(budget.Daily) {
+		warning = u.Warned
+		u.Warned++
+	}
+	if warning == -1 {
+		return nil
+	}
+	return &budgetNotice{
+		message: fmt.Sprintf(
+			"You've used %v%% of your %v %s budget for today.", warnings[warning], budget.Daily, rule.Name,
+		),
+		urgency: notify.Normal,
+	}
+}
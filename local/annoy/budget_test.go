@@ -0,0 +1,69 @@
+package annoy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBudgetDayStart(t *testing.T) {
+	budget := Budget{Daily: time.Hour, Reset: 4*time.Hour + 30*time.Minute}
+	assert.Equal(
+		t,
+		time.Date(2018, time.September, 2, 4, 30, 0, 0, time.Local),
+		budget.dayStart(time.Date(2018, time.September, 3, 4, 29, 0, 0, time.Local)),
+	)
+	assert.Equal(
+		t,
+		time.Date(2018, time.September, 3, 4, 30, 0, 0, time.Local),
+		budget.dayStart(time.Date(2018, time.September, 3, 23, 0, 0, 0, time.Local)),
+	)
+
+	// The budget starts over at 4:30 on the clock, even on the days the clocks change.
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	for _, day := range []int{10, 11, 12} {
+		assert.Equal(
+			t,
+			time.Date(2018, time.March, day, 4, 30, 0, 0, newYork),
+			budget.dayStart(time.Date(2018, time.March, day, 12, 0, 0, 0, newYork)),
+		)
+	}
+	assert.Equal(
+		t,
+		time.Date(2018, time.November, 3, 4, 30, 0, 0, newYork),
+		budget.dayStart(time.Date(2018, time.November, 4, 4, 0, 0, 0, newYork)),
+	)
+	assert.Equal(
+		t,
+		time.Date(2018, time.November, 4, 4, 30, 0, 0, newYork),
+		budget.dayStart(time.Date(2018, time.November, 4, 4, 30, 0, 0, newYork)),
+	)
+}
+
+func TestBudgetDue(t *testing.T) {
+	rule := Rule{Name: "email", Budget: Budget{Daily: 100 * time.Minute, Warnings: []float64{25, 50, 75}}}
+	usage := &BudgetUsage{}
+	for _, testCase := range []struct {
+		used    time.Duration
+		message string
+	}{
+		{10 * time.Minute, ""},
+		{25 * time.Minute, "You've used 25% of your 1h40m0s email budget for today."},
+		{30 * time.Minute, ""},
+		// Jumping past several warnings only sends the last one.
+		{80 * time.Minute, "You've used 75% of your 1h40m0s email budget for today."},
+		{100 * time.Minute, "Your 1h40m0s email budget for today is used up."},
+		{200 * time.Minute, ""},
+	} {
+		usage.Used = testCase.used
+		notice := usage.due(rule)
+		if testCase.message == "" {
+			assert.Nil(t, notice, testCase.used)
+		} else if assert.NotNil(t, notice, testCase.used) {
+			assert.Equal(t, testCase.message, notice.message)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package annoy
+
+import (
+	"time"
+
+	"github.com/dwetterau/glider/local/enforce"
+	"github.com/dwetterau/glider/local/notify"
+	"github.com/dwetterau/glider/local/xscan"
+)
+
+// One step of a rule's escalation policy.
+type Step struct {
+	// How far past the rule's bucket size the bucket has to get for the step to fire.
+	After time.Duration
+
+	Urgency notify.Urgency
+	// Overrides the rule's message.
+	Message string
+	// Something to do to the offending window besides notifying, see the Action
+	// constants.
+	Action string
+	// The window class a focus action switches to. Defaults to the enforcer's work class.
+	Target string
+	// Log the action instead of taking it.
+	DryRun bool
+}
+
+// Actions that escalation steps can take.
+const (
+	ActionNone     = ""
+	ActionMinimize = enforce.ActionMinimize
+	ActionFocus    = enforce.ActionFocus
+	ActionClose    = enforce.ActionClose
+)
+
+// A step that fired, for the daemon status.
+type Escalation struct {
+	Time   time.Time
+	Step   int
+	Window xscan.Window
+}
+
+// How many fired steps to remember per rule.
+const historySize = 10
+
+// Where a rule is in its escalation policy.
+type escalation struct {
+	// The index of the next step to fire.
+	next int
+	// How far past the bucket size the bucket was when a step last fired.
+	lastFired time.Duration
+	history   []Escalation
+}
+
+// Returns the step that should fire now that the bucket is overflow past its size, or -1
+// if there's nothing new to fire. When several steps become due at once only the last of
+// them fires.
+func (e *escalation) due(rule Rule, overflow time.Duration) int {
+	steps := rule.steps()
+	step := -1
+	for e.next < len(steps) && overflow >= steps[e.next].After {
+		step = e.next
+		e.next++
+	}
+	if step == -1 && e.next == len(steps) && rule.repeat() > 0 && overflow-e.lastFired >= rule.repeat() {
+		step = len(steps) - 1
+	}
+	if step != -1 {
+		e.lastFired = overflow
+	}
+	return step
+}
+
+// Starts the policy over once the bucket is back under its size.
+func (e *escalation) reset() {
+	e.next = 0
+	e.lastFired = 0
+}
+
+// Returns the escalation to keep using now that the rule changed from one version to
+// another. Where the policy is at only means something for the same steps, so it starts
+// over if they changed.
+func (e *escalation) carryOver(from, to Rule) *escalation {
+	fromSteps, toSteps := from.steps(), to.steps()
+	same := len(fromSteps) == len(toSteps)
+	for i := 0; same && i < len(fromSteps); i++ {
+		same = fromSteps[i] == toSteps[i]
+	}
+	if !same {
+		e.reset()
+	}
+	return e
+}
+
+func (e *escalation) fired(now time.Time, step int, window xscan.Window) {
+	e.history = append(e.history, Escalation{Time: now, Step: step, Window: window})
+	if len(e.history) > historySize {
+		e.history = e.history[len(e.history)-historySize:]
+	}
+}
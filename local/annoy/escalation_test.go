@@ -0,0 +1,54 @@
+package annoy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dwetterau/glider/local/notify"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEscalationDue(t *testing.T) {
+	rule := Rule{
+		BucketSize: 3 * time.Minute,
+		Escalation: []Step{
+			{After: 0},
+			{After: 5 * time.Minute, Urgency: notify.Critical},
+			{After: 10 * time.Minute, Action: ActionMinimize},
+		},
+		Repeat: 2 * time.Minute,
+	}
+	e := &escalation{}
+	for _, testCase := range []struct {
+		overflow time.Duration
+		step     int
+	}{
+		{time.Second, 0},
+		{time.Minute, -1},
+		{5 * time.Minute, 1},
+		{9 * time.Minute, -1},
+		{10 * time.Minute, 2},
+		{11 * time.Minute, -1},
+		// The last step repeats every Repeat.
+		{12 * time.Minute, 2},
+		{13 * time.Minute, -1},
+		{14 * time.Minute, 2},
+	} {
+		assert.Equal(t, testCase.step, e.due(rule, testCase.overflow), testCase.overflow)
+	}
+
+	// Jumping past several steps at once only fires the last one.
+	e.reset()
+	assert.Equal(t, 1, e.due(rule, 6*time.Minute))
+	assert.Equal(t, 2, e.due(rule, 10*time.Minute))
+}
+
+func TestDefaultEscalation(t *testing.T) {
+	// Without a policy, nag once and then again every time another bucket fills up.
+	rule := Rule{BucketSize: 3 * time.Minute}
+	e := &escalation{}
+	assert.Equal(t, 0, e.due(rule, time.Second))
+	assert.Equal(t, -1, e.due(rule, 3*time.Minute))
+	assert.Equal(t, 0, e.due(rule, 3*time.Minute+time.Second))
+	assert.Equal(t, Step{Urgency: notify.Normal}, rule.Step(0))
+}
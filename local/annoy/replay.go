@@ -0,0 +1,23 @@
+package annoy
+
+import (
+	"github.com/dwetterau/glider/local/xscan"
+)
+
+// Feeds a recording through the annoyer as fast as it can, charging the time until each
+// sample to the window that was focused before it, like the daemon does. Samples where
+// the scanner failed leave the previous window focused. The annoyer has to go by the
+// replayer's clock.
+func Replay(a Annoyer, replayer *xscan.Replayer) {
+	var window xscan.Window
+	for {
+		if current, err := replayer.CurrentWindow(); err == nil {
+			window = current
+		}
+		elapsed, ok := replayer.Step()
+		if !ok {
+			return
+		}
+		a.MaybeAnnoy(window, elapsed)
+	}
+}
@@ -0,0 +1,74 @@
+package annoy
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dwetterau/glider/local/clock"
+	"github.com/dwetterau/glider/local/notify"
+	"github.com/dwetterau/glider/local/xscan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Notes when each notification was sent, by the replay's clock.
+type timedRecorder struct {
+	lock  sync.Mutex
+	clock clock.Clock
+	nags  []string
+}
+
+func (r *timedRecorder) Notify(n notify.Notification) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.nags = append(r.nags, r.clock.Now().UTC().Format("15:04:05")+" "+n.Body)
+	return nil
+}
+
+func replay(t *testing.T, recording string, rules []Rule) []string {
+	f, err := os.Open(filepath.Join("testdata", recording))
+	require.NoError(t, err)
+	defer f.Close()
+	samples, err := xscan.ReadRecording(f)
+	require.NoError(t, err)
+
+	c := clock.NewFake(time.Time{})
+	replayer := xscan.NewReplayer(samples, c)
+	recorder := &timedRecorder{clock: c}
+	a := NewAnnoyerWithClock(rules, map[string]notify.Notifier{DefaultNotifier: recorder}, nil, c)
+	Replay(a, replayer)
+	return recorder.nags
+}
+
+// A morning of work with a few trips to Slack and Gmail, recorded every 30 seconds.
+func TestReplayDefaultRules(t *testing.T) {
+	assert.Equal(t, []string{
+		"09:23:30 Stop reading Slack.",
+		"09:45:30 Read your email faster or not at all.",
+		"09:50:30 Read your email faster or not at all.",
+		// Quick looks at Slack add up, since the bucket only drains at half speed.
+		"10:41:00 Stop reading Slack.",
+		"10:53:00 Stop reading Slack.",
+	}, replay(t, "morning.jsonl", DefaultRules()))
+}
+
+func TestReplayEscalation(t *testing.T) {
+	rules := []Rule{{
+		Name:            "slack",
+		ApplicationName: "Slack",
+		BucketSize:      2 * time.Minute,
+		DrainFactor:     1,
+		Escalation: []Step{
+			{Message: "Slack again."},
+			{After: 2 * time.Minute, Message: "Close Slack.", Urgency: notify.Critical},
+		},
+	}}
+	// Draining at full speed, the quick looks never add up.
+	assert.Equal(t, []string{
+		"09:22:30 Slack again.",
+		"09:24:00 Close Slack.",
+	}, replay(t, "morning.jsonl", rules))
+}
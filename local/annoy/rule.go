@@ -0,0 +1,159 @@
+package annoy
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dwetterau/glider/local/browser"
+	"github.com/dwetterau/glider/local/notify"
+	"github.com/dwetterau/glider/local/schedule"
+	"github.com/dwetterau/glider/local/xscan"
+)
+
+// Decides which windows fill a bucket, how big that bucket is and what to say once it
+// overflows.
+type Rule struct {
+	// Identifies the rule in messages and keys its bucket.
+	Name string
+
+	// Every matcher that is set has to match for a window to count towards the rule.
+	// ApplicationName is compared case insensitively against the window's application
+	// name, Executable against the base name of the window's process executable and
+	// Domain against the site a browser window shows, including its subdomains.
+	ApplicationName string
+	Title           *regexp.Regexp
+	Executable      string
+	Domain          string
+
+	// The annoyer will annoy if the rule's windows accumulate this amount of duration.
+	// Zero means the rule only has a Budget.
+	BucketSize time.Duration
+
+	// A value in [0, inf) that is multiplied by the time elapsed and subtracted from the
+	// accumulated bucket when none of the rule's windows are active.
+	DrainFactor float64
+
+	// The text of the notification.
+	Message string
+
+	// The names of the notifiers to send notifications to. Defaults to DefaultNotifier.
+	Notifiers []string
+
+	// What to do once the bucket overflows, in order of increasing After. Defaults to a
+	// single notification with normal urgency.
+	Escalation []Step
+
+	// Limits the rule's windows to some total time per day, on top of the bucket.
+	Budget Budget
+
+	// When the rule applies, in local time. Outside of it the rule's windows don't fill
+	// its bucket and it doesn't annoy. Defaults to always.
+	Schedule schedule.Schedule
+
+	// The rule's windows count as this category towards the focus score, see the
+	// category package. Defaults to distracting.
+	Category string
+
+	// Fire the last escalation step again every time the bucket grows this much more.
+	// Zero means never, unless there's no Escalation, in which case the default step
+	// repeats every BucketSize.
+	Repeat time.Duration
+}
+
+// The notifier that's used by rules that don't name any.
+const DefaultNotifier = "desktop"
+
+const defaultMessage = "Shouldn't you be doing something else?"
+
+// The rules that are used when there's no config file.
+func DefaultRules() []Rule {
+	return []Rule{
+		{
+			Name:        "slack",
+			Title:       regexp.MustCompile(` \| .* Slack\b`),
+			BucketSize:  3 * time.Minute,
+			DrainFactor: .5,
+			Message:     "Stop reading Slack.",
+		},
+		{
+			Name:        "gmail",
+			Title:       regexp.MustCompile(`\S+@\S+\.\S+.+G?[mM]ail -`),
+			BucketSize:  5 * time.Minute,
+			DrainFactor: 10,
+			Message:     "Read your email faster or not at all.",
+		},
+	}
+}
+
+func (r Rule) Matches(window xscan.Window) bool {
+	if r.ApplicationName != "" && !strings.EqualFold(r.ApplicationName, window.ApplicationName) {
+		return false
+	}
+	if r.Title != nil && !r.Title.MatchString(window.Title) {
+		return false
+	}
+	if r.Executable != "" && r.Executable != xscan.Executable(window.PID) {
+		return false
+	}
+	if r.Domain != "" && (window.Domain == "" || !browser.InDomain(window.Domain, r.Domain)) {
+		return false
+	}
+	return true
+}
+
+// Reports whether the rule's schedule is active at t.
+func (r Rule) Active(t time.Time) bool {
+	return r.Schedule.Active(t)
+}
+
+// Two rules with the same identity fill their buckets with the same windows.
+func (r Rule) identity() string {
+	title := ""
+	if r.Title != nil {
+		title = r.Title.String()
+	}
+	return strings.Join(
+		[]string{r.Name, strings.ToLower(r.ApplicationName), title, r.Executable, strings.ToLower(r.Domain)}, "\x00",
+	)
+}
+
+// Returns the i-th step of the rule's escalation policy, or of the default one.
+func (r Rule) Step(i int) Step {
+	steps := r.steps()
+	if i < 0 || i >= len(steps) {
+		return Step{}
+	}
+	return steps[i]
+}
+
+func (r Rule) steps() []Step {
+	if len(r.Escalation) == 0 {
+		return []Step{{Urgency: notify.Normal}}
+	}
+	return r.Escalation
+}
+
+func (r Rule) repeat() time.Duration {
+	if r.Repeat == 0 && len(r.Escalation) == 0 {
+		return r.BucketSize
+	}
+	return r.Repeat
+}
+
+func (r Rule) notifierNames() []string {
+	if len(r.Notifiers) == 0 {
+		return []string{DefaultNotifier}
+	}
+	return r.Notifiers
+}
+
+func (r Rule) message(step Step) string {
+	if step.Message != "" {
+		return step.Message
+	}
+	if r.Message == "" {
+		return defaultMessage
+	}
+	return r.Message
+}
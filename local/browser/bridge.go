@@ -0,0 +1,107 @@
+package browser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// What a browser extension reports about the active tab.
+type Tab struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+}
+
+// Reports older than this are ignored, in case the extension stopped reporting.
+const tabTimeout = 10 * time.Minute
+
+// The most a report can be.
+const maxReportSize = 64 << 10
+
+// Listens on localhost for a browser extension to report the active tab, which is more
+// precise than guessing the site from the window title. The extension POSTs a JSON Tab
+// to /active whenever the active tab or its URL changes.
+type Bridge struct {
+	listener net.Listener
+	token    string
+
+	lock     sync.Mutex
+	active   Tab
+	reported time.Time
+}
+
+// Starts listening on address, which has to be a loopback address like
+// "127.0.0.1:8377". If token isn't empty, reports have to carry it as a bearer token.
+func ServeBridge(address, token string) (*Bridge, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return nil, fmt.Errorf("the bridge only listens on loopback addresses, not %s", host)
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	b := &Bridge{listener: listener, token: token}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/active", b.handleActive)
+	go http.Serve(listener, mux)
+	return b, nil
+}
+
+func (b *Bridge) Addr() net.Addr {
+	return b.listener.Addr()
+}
+
+func (b *Bridge) Close() error {
+	return b.listener.Close()
+}
+
+// Returns the tab that was last reported, unless that was too long ago.
+func (b *Bridge) Active() (Tab, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.reported.IsZero() || time.Since(b.reported) > tabTimeout {
+		return Tab{}, false
+	}
+	return b.active, true
+}
+
+func (b *Bridge) handleActive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "use POST", http.StatusMethodNotAllowed)
+		return
+	}
+	// Requiring JSON makes browsers preflight requests from web pages, which we never
+	// allow, so only extensions and local programs can report.
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		http.Error(w, "expected application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+	if origin := r.Header.Get("Origin"); strings.HasPrefix(origin, "http://") || strings.HasPrefix(origin, "https://") {
+		http.Error(w, "web pages can't report tabs", http.StatusForbidden)
+		return
+	}
+	if b.token != "" && r.Header.Get("Authorization") != "Bearer "+b.token {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+	var tab Tab
+	err := json.NewDecoder(io.LimitReader(r.Body, maxReportSize)).Decode(&tab)
+	if err != nil || tab.URL == "" {
+		http.Error(w, "expected a JSON object with a url and a title", http.StatusBadRequest)
+		return
+	}
+	b.lock.Lock()
+	b.active = tab
+	b.reported = time.Now()
+	b.lock.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
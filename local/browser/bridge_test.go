@@ -0,0 +1,64 @@
+package browser
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/dwetterau/glider/local/xscan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func report(t *testing.T, b *Bridge, body string, headers map[string]string) int {
+	request, err := http.NewRequest(
+		http.MethodPost, fmt.Sprintf("http://%s/active", b.Addr()), bytes.NewBufferString(body),
+	)
+	require.NoError(t, err)
+	request.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	response.Body.Close()
+	return response.StatusCode
+}
+
+func TestBridge(t *testing.T) {
+	b, err := ServeBridge("127.0.0.1:0", "secret")
+	require.NoError(t, err)
+	defer b.Close()
+	auth := map[string]string{"Authorization": "Bearer secret"}
+
+	_, ok := b.Active()
+	assert.False(t, ok)
+
+	tab := `{"url": "https://lobste.rs/t/go", "title": "Go | Lobsters"}`
+	assert.Equal(t, http.StatusUnauthorized, report(t, b, tab, nil))
+	assert.Equal(t, http.StatusForbidden, report(t, b, tab, map[string]string{
+		"Authorization": "Bearer secret", "Origin": "https://evil.example.com",
+	}))
+	assert.Equal(t, http.StatusUnsupportedMediaType, report(t, b, tab, map[string]string{
+		"Authorization": "Bearer secret", "Content-Type": "text/plain",
+	}))
+	assert.Equal(t, http.StatusBadRequest, report(t, b, `{"title": "no url"}`, auth))
+	assert.Equal(t, http.StatusNoContent, report(t, b, tab, auth))
+
+	active, ok := b.Active()
+	require.True(t, ok)
+	assert.Equal(t, Tab{URL: "https://lobste.rs/t/go", Title: "Go | Lobsters"}, active)
+
+	// The reported URL wins over guessing, as long as it's for the focused page.
+	c := NewClassifier(nil, b)
+	window := xscan.Window{ApplicationName: "firefox", Title: "Go | Lobsters — Mozilla Firefox"}
+	assert.Equal(t, "lobste.rs", c.Classify(window).Domain)
+	window.Title = "Other - YouTube — Mozilla Firefox"
+	assert.Equal(t, "youtube.com", c.Classify(window).Domain)
+}
+
+func TestBridgeOnlyListensLocally(t *testing.T) {
+	_, err := ServeBridge("0.0.0.0:0", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,173 @@
+package browser
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dwetterau/glider/local/xscan"
+)
+
+// How to recognize a browser's windows and get the page title out of their titles.
+type Browser struct {
+	Name string
+	// Matches the window's application name.
+	Application *regexp.Regexp
+	// Matches the whole window title. The first group is the page title.
+	Title *regexp.Regexp
+}
+
+var DefaultBrowsers = []Browser{
+	{
+		Name:        "chrome",
+		Application: regexp.MustCompile(`(?i)^(google-chrome|chromium|brave-browser|microsoft-edge|vivaldi)`),
+		Title:       regexp.MustCompile(`^(.*) - (Google Chrome|Chromium|Brave|Microsoft\x{200b}? Edge|Vivaldi)$`),
+	},
+	{
+		Name:        "firefox",
+		Application: regexp.MustCompile(`(?i)^(firefox|navigator|librewolf)`),
+		Title:       regexp.MustCompile(`^(.*) [—-] (Mozilla Firefox|LibreWolf)$`),
+	},
+}
+
+// Page titles of popular sites name the site at their start or end, e.g. "Home / X" or
+// "GitHub - dwetterau/glider". Keyed by the name in the title.
+var DefaultSites = map[string]string{
+	"Facebook":       "facebook.com",
+	"GitHub":         "github.com",
+	"Gmail":          "mail.google.com",
+	"Hacker News":    "news.ycombinator.com",
+	"Instagram":      "instagram.com",
+	"LinkedIn":       "linkedin.com",
+	"Netflix":        "netflix.com",
+	"Reddit":         "reddit.com",
+	"Slack":          "slack.com",
+	"Stack Overflow": "stackoverflow.com",
+	"Twitch":         "twitch.tv",
+	"Twitter":        "twitter.com",
+	"Wikipedia":      "wikipedia.org",
+	"X":              "x.com",
+	"YouTube":        "youtube.com",
+}
+
+// What separates a site's name from the rest of a page title.
+var separators = []string{" - ", " | ", " / ", " – ", " — ", " · "}
+
+// Some extensions add the hostname to the end of the page title, e.g. "Title - example.com".
+var hostSuffix = regexp.MustCompile(`\s[-|–—]\s((?:[a-z0-9-]+\.)+[a-z]{2,})$`)
+
+// Figures out which site browser windows are showing, from their titles or from what a
+// browser extension reported to the Bridge.
+type Classifier struct {
+	lock  sync.Mutex
+	sites map[string]string
+	// The site names, longest first so that "Hacker News" wins over "News".
+	names  []string
+	bridge *Bridge
+}
+
+// Sites extends DefaultSites. The bridge can be nil.
+func NewClassifier(sites map[string]string, bridge *Bridge) *Classifier {
+	c := &Classifier{bridge: bridge}
+	c.SetSites(sites)
+	return c
+}
+
+func (c *Classifier) SetSites(sites map[string]string) {
+	merged := make(map[string]string, len(DefaultSites)+len(sites))
+	for name, domain := range DefaultSites {
+		merged[name] = domain
+	}
+	for name, domain := range sites {
+		merged[name] = domain
+	}
+	names := make([]string, 0, len(merged))
+	for name := range merged {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) > len(names[j])
+		}
+		return names[i] < names[j]
+	})
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.sites = merged
+	c.names = names
+}
+
+// Returns the window with its Domain filled in, if it's a browser window showing a site
+// that can be told apart.
+func (c *Classifier) Classify(window xscan.Window) xscan.Window {
+	pageTitle, ok := PageTitle(window)
+	if !ok {
+		return window
+	}
+	if c.bridge != nil {
+		if active, ok := c.bridge.Active(); ok && active.Title == pageTitle {
+			if domain := Domain(active.URL); domain != "" {
+				window.Domain = domain
+				return window
+			}
+		}
+	}
+	if match := hostSuffix.FindStringSubmatch(pageTitle); match != nil {
+		window.Domain = strings.TrimPrefix(match[1], "www.")
+		return window
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, name := range c.names {
+		if siteInTitle(name, pageTitle) {
+			window.Domain = c.sites[name]
+			return window
+		}
+	}
+	return window
+}
+
+// Returns the title of the page a browser window is showing, without the browser's
+// name. Reports false for windows that aren't from a known browser.
+func PageTitle(window xscan.Window) (string, bool) {
+	for _, b := range DefaultBrowsers {
+		if !b.Application.MatchString(window.ApplicationName) {
+			continue
+		}
+		if match := b.Title.FindStringSubmatch(window.Title); match != nil {
+			return match[1], true
+		}
+		// Windows without a page, e.g. a new tab, only have the browser's name.
+		return "", true
+	}
+	return "", false
+}
+
+func siteInTitle(name, title string) bool {
+	if title == name {
+		return true
+	}
+	for _, separator := range separators {
+		if strings.HasSuffix(title, separator+name) || strings.HasPrefix(title, name+separator) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the lowercase hostname of the URL without any "www.", or "" if it has none.
+func Domain(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// Reports whether domain is the given domain or one of its subdomains.
+func InDomain(domain, parent string) bool {
+	domain, parent = strings.ToLower(domain), strings.ToLower(parent)
+	return domain == parent || strings.HasSuffix(domain, "."+parent)
+}
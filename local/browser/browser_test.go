@@ -0,0 +1,48 @@
+package browser
+
+import (
+	"testing"
+
+	"github.com/dwetterau/glider/local/xscan"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	c := NewClassifier(map[string]string{"Lobsters": "lobste.rs"}, nil)
+	for _, testCase := range []struct {
+		window xscan.Window
+		domain string
+	}{
+		{xscan.Window{ApplicationName: "Google-chrome", Title: "Go - YouTube - Google Chrome"}, "youtube.com"},
+		{xscan.Window{ApplicationName: "firefox", Title: "Home / X — Mozilla Firefox"}, "x.com"},
+		{
+			xscan.Window{ApplicationName: "firefox", Title: "Inbox (3) - me@example.com - Gmail — Mozilla Firefox"},
+			"mail.google.com",
+		},
+		{
+			xscan.Window{ApplicationName: "Chromium", Title: "GitHub - dwetterau/glider: Stay focused - Chromium"},
+			"github.com",
+		},
+		{xscan.Window{ApplicationName: "firefox", Title: "Hacker News — Mozilla Firefox"}, "news.ycombinator.com"},
+		{xscan.Window{ApplicationName: "firefox", Title: "Lobsters — Mozilla Firefox"}, "lobste.rs"},
+		// Extensions can add the hostname to the title.
+		{xscan.Window{ApplicationName: "firefox", Title: "Some article - www.example.com — Mozilla Firefox"}, "example.com"},
+		// Filenames aren't hostnames.
+		{xscan.Window{ApplicationName: "firefox", Title: "README.md — Mozilla Firefox"}, ""},
+		{xscan.Window{ApplicationName: "firefox", Title: "Mozilla Firefox"}, ""},
+		// Only browsers have domains.
+		{xscan.Window{ApplicationName: "Code", Title: "notes - YouTube"}, ""},
+	} {
+		assert.Equal(t, testCase.domain, c.Classify(testCase.window).Domain, testCase.window.Title)
+	}
+}
+
+func TestDomain(t *testing.T) {
+	assert.Equal(t, "youtube.com", Domain("https://www.YouTube.com/watch?v=1"))
+	assert.Equal(t, "localhost", Domain("http://localhost:8080/"))
+	assert.Equal(t, "", Domain("about:blank"))
+
+	assert.True(t, InDomain("youtube.com", "youtube.com"))
+	assert.True(t, InDomain("m.youtube.com", "YouTube.com"))
+	assert.False(t, InDomain("notyoutube.com", "youtube.com"))
+}
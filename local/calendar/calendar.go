@@ -0,0 +1,175 @@
+package calendar
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A single occurrence of an event.
+type Occurrence struct {
+	Event      *Event
+	Start, End time.Time
+}
+
+// Reads the events of every .ics file at the paths. A path can also be a directory, like
+// a CalDAV export, in which case every .ics file in it and its subdirectories is read.
+// Events are returned even if some files or events couldn't be read, along with an error
+// that says which.
+func Load(paths []string) ([]Event, error) {
+	var events []Event
+	var problems []string
+	for _, path := range files(paths, &problems) {
+		f, err := os.Open(path)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		fileEvents, err := Parse(f)
+		f.Close()
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", path, err))
+		}
+		events = append(events, fileEvents...)
+	}
+	if len(problems) > 0 {
+		return events, fmt.Errorf("%s", strings.Join(problems, "\n"))
+	}
+	return events, nil
+}
+
+// Returns the latest modification time of the calendar files at the paths, so that
+// callers can tell when to load them again.
+func LastModified(paths []string) time.Time {
+	var latest time.Time
+	var problems []string
+	for _, path := range append(files(paths, &problems), paths...) {
+		if info, err := os.Stat(path); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
+
+func files(paths []string, problems *[]string) []string {
+	var files []string
+	for _, path := range paths {
+		err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && (file == path || strings.EqualFold(filepath.Ext(file), ".ics")) {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			*problems = append(*problems, err.Error())
+		}
+	}
+	return files
+}
+
+// Returns the occurrences of the events that overlap [from, to), ordered by start.
+// Overrides of single occurrences of recurring events take the place of the occurrence
+// they override.
+func Occurrences(events []Event, from, to time.Time) []Occurrence {
+	type key struct {
+		uid   string
+		start int64
+	}
+	overridden := make(map[key]bool)
+	for _, event := range events {
+		if !event.RecurrenceID.IsZero() {
+			overridden[key{event.UID, event.RecurrenceID.UnixNano()}] = true
+		}
+	}
+	var occurrences []Occurrence
+	add := func(event *Event, start time.Time) {
+		end := start.Add(event.Duration)
+		if start.Before(to) && (end.After(from) || (event.Duration == 0 && !start.Before(from))) {
+			occurrences = append(occurrences, Occurrence{Event: event, Start: start, End: end})
+		}
+	}
+	for i := range events {
+		event := &events[i]
+		if event.Recurrence == nil || !event.RecurrenceID.IsZero() {
+			add(event, event.Start)
+			continue
+		}
+		event.Recurrence.each(event.Start, to, func(start time.Time) bool {
+			if !start.Before(to) {
+				return false
+			}
+			if overridden[key{event.UID, start.UnixNano()}] {
+				return true
+			}
+			for _, exception := range event.Exceptions {
+				if exception.Equal(start) || (event.AllDay && exception.Equal(startOfDay(start))) {
+					return true
+				}
+			}
+			add(event, start)
+			return true
+		})
+	}
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		return occurrences[i].Start.Before(occurrences[j].Start)
+	})
+	return occurrences
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// Like Occurrences, but only of meetings.
+func Meetings(events []Event, from, to time.Time) []Occurrence {
+	var meetings []Occurrence
+	for _, occurrence := range Occurrences(events, from, to) {
+		if occurrence.Event.Meeting() {
+			meetings = append(meetings, occurrence)
+		}
+	}
+	return meetings
+}
+
+// Returns the occurrence going on at t, or nil if there's none. If there are several,
+// it's the one that ends last.
+func At(occurrences []Occurrence, t time.Time) *Occurrence {
+	var current *Occurrence
+	for i, occurrence := range occurrences {
+		if !t.Before(occurrence.Start) && t.Before(occurrence.End) {
+			if current == nil || occurrence.End.After(current.End) {
+				current = &occurrences[i]
+			}
+		}
+	}
+	return current
+}
+
+// Returns how much of [from, to) the occurrences, which are ordered by start, take up.
+// Overlapping occurrences only count once.
+func Total(occurrences []Occurrence, from, to time.Time) time.Duration {
+	var total time.Duration
+	// The end of the time that's been counted so far.
+	counted := from
+	for _, occurrence := range occurrences {
+		start, end := occurrence.Start, occurrence.End
+		if start.Before(counted) {
+			start = counted
+		}
+		if end.After(to) {
+			end = to
+		}
+		if end.After(start) {
+			total += end.Sub(start)
+			counted = end
+		}
+	}
+	return total
+}
@@ -0,0 +1,234 @@
+package calendar
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parse(t *testing.T, events string) []Event {
+	parsed, err := Parse(strings.NewReader("BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" + events + "END:VCALENDAR\r\n"))
+	require.NoError(t, err)
+	return parsed
+}
+
+func starts(occurrences []Occurrence) []string {
+	var starts []string
+	for _, occurrence := range occurrences {
+		starts = append(starts, occurrence.Start.Format("Mon 2006-01-02 15:04 MST"))
+	}
+	return starts
+}
+
+func TestParse(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	events := parse(t, `BEGIN:VEVENT
+UID:1
+SUMMARY:Planning\, part 2
+DTSTART;TZID="/mozilla.org/20050126_1/Europe/Berlin":20180903T100000
+DTEND;TZID=Europe/Berlin:20180903T113000
+ATTENDEE;CN="Someone: else";PARTSTAT=ACCEPTED:mailto:someone@exa
+ mple.com
+BEGIN:VALARM
+TRIGGER:-PT15M
+DURATION:PT5M
+END:VALARM
+END:VEVENT
+BEGIN:VEVENT
+DTEND;TZID=Eastern Standard Time:20180903T100000
+DTSTART;TZID=Eastern Standard Time:20180903T093000
+SUMMARY:Outlook puts the end first
+CATEGORIES:Work,Meeting
+END:VEVENT
+BEGIN:VEVENT
+SUMMARY:Lunch
+DTSTART:20180903T110000Z
+DURATION:PT1H
+TRANSP:TRANSPARENT
+END:VEVENT
+BEGIN:VEVENT
+SUMMARY:Holiday
+DTSTART;VALUE=DATE:20180904
+END:VEVENT
+`)
+	require.Len(t, events, 4)
+
+	assert.Equal(t, "Planning, part 2", events[0].Summary)
+	assert.Equal(t, time.Date(2018, 9, 3, 10, 0, 0, 0, berlin), events[0].Start)
+	assert.Equal(t, 90*time.Minute, events[0].Duration)
+	assert.Equal(t, 1, events[0].Attendees)
+	assert.True(t, events[0].Meeting())
+
+	assert.Equal(t, time.Date(2018, 9, 3, 9, 30, 0, 0, newYork), events[1].Start)
+	assert.Equal(t, 30*time.Minute, events[1].Duration)
+	assert.Equal(t, []string{"Work", "Meeting"}, events[1].Categories)
+	assert.True(t, events[1].Meeting())
+
+	assert.Equal(t, time.Date(2018, 9, 3, 11, 0, 0, 0, time.UTC), events[2].Start)
+	assert.Equal(t, time.Hour, events[2].Duration)
+	assert.False(t, events[2].Meeting())
+
+	assert.True(t, events[3].AllDay)
+	assert.Equal(t, time.Date(2018, 9, 4, 0, 0, 0, 0, time.Local), events[3].Start)
+	assert.Equal(t, 24*time.Hour, events[3].Duration)
+	assert.False(t, events[3].Meeting())
+}
+
+func TestParseErrors(t *testing.T) {
+	events, err := Parse(strings.NewReader(`BEGIN:VCALENDAR
+BEGIN:VEVENT
+SUMMARY:Every hour
+DTSTART:20180903T090000Z
+RRULE:FREQ=HOURLY
+END:VEVENT
+BEGIN:VEVENT
+SUMMARY:Fine
+DTSTART:20180903T090000Z
+END:VEVENT
+END:VCALENDAR
+`))
+	assert.EqualError(t, err, `skipped 1 events: "Every hour": line 5: RRULE: FREQ=HOURLY isn't supported`)
+	require.Len(t, events, 1)
+	assert.Equal(t, "Fine", events[0].Summary)
+
+	_, err = Parse(strings.NewReader("BEGIN:VCALENDAR\nBEGIN:VEVENT\nEND:VCALENDAR\n"))
+	assert.EqualError(t, err, "line 3: END:VCALENDAR without a BEGIN")
+}
+
+func TestRecurrence(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	from := time.Date(2018, 10, 29, 0, 0, 0, 0, newYork)
+	to := time.Date(2019, 1, 1, 0, 0, 0, 0, newYork)
+
+	for _, testCase := range []struct {
+		name     string
+		event    string
+		expected []string
+	}{
+		{
+			name: "weekly, keeping the time of day across the end of daylight saving time",
+			event: `DTSTART;TZID=America/New_York:20180903T093000
+RRULE:FREQ=WEEKLY;BYDAY=MO,TH;UNTIL=20181109T000000Z`,
+			expected: []string{
+				"Mon 2018-10-29 09:30 EDT", "Thu 2018-11-01 09:30 EDT",
+				"Mon 2018-11-05 09:30 EST", "Thu 2018-11-08 09:30 EST",
+			},
+		},
+		{
+			name: "every other day with exceptions and an end",
+			event: `DTSTART;TZID=America/New_York:20181027T090000
+RRULE:FREQ=DAILY;INTERVAL=2;UNTIL=20181106T235959Z
+EXDATE;TZID=America/New_York:20181031T090000,20181102T090000`,
+			expected: []string{"Mon 2018-10-29 09:00 EDT", "Sun 2018-11-04 09:00 EST", "Tue 2018-11-06 09:00 EST"},
+		},
+		{
+			name: "the last Friday of the month, a few times",
+			event: `DTSTART;TZID=America/New_York:20180928T160000
+RRULE:FREQ=MONTHLY;BYDAY=-1FR;COUNT=3`,
+			expected: []string{"Fri 2018-11-30 16:00 EST"},
+		},
+		{
+			name: "the 31st, skipping short months",
+			event: `DTSTART;TZID=America/New_York:20180831T120000
+RRULE:FREQ=MONTHLY`,
+			expected: []string{"Wed 2018-10-31 12:00 EDT", "Mon 2018-12-31 12:00 EST"},
+		},
+		{
+			name: "yearly on the second Monday in November",
+			event: `DTSTART;TZID=America/New_York:20171113T120000
+RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=2MO`,
+			expected: []string{"Mon 2018-11-12 12:00 EST"},
+		},
+	} {
+		events := parse(t, "BEGIN:VEVENT\n"+testCase.event+"\nDURATION:PT30M\nEND:VEVENT\n")
+		occurrences := Occurrences(events, from, to)
+		assert.Equal(t, testCase.expected, starts(occurrences), testCase.name)
+	}
+}
+
+func TestMeetings(t *testing.T) {
+	events := parse(t, `BEGIN:VEVENT
+UID:standup
+SUMMARY:Standup
+DTSTART:20180903T090000Z
+DURATION:PT15M
+RRULE:FREQ=DAILY;COUNT=3
+ATTENDEE:mailto:team@example.com
+END:VEVENT
+BEGIN:VEVENT
+UID:standup
+RECURRENCE-ID:20180904T090000Z
+SUMMARY:Long standup
+DTSTART:20180904T083000Z
+DURATION:PT1H
+ATTENDEE:mailto:team@example.com
+END:VEVENT
+BEGIN:VEVENT
+SUMMARY:Focus time
+DTSTART:20180904T090000Z
+DURATION:PT2H
+END:VEVENT
+BEGIN:VEVENT
+SUMMARY:Planning
+DTSTART:20180904T091500Z
+DURATION:PT30M
+CATEGORIES:MEETING
+END:VEVENT
+`)
+	from := time.Date(2018, 9, 3, 0, 0, 0, 0, time.UTC)
+	meetings := Meetings(events, from, from.AddDate(0, 0, 7))
+	var summaries []string
+	for _, meeting := range meetings {
+		summaries = append(summaries, meeting.Event.Summary)
+	}
+	assert.Equal(t, []string{"Standup", "Long standup", "Planning", "Standup"}, summaries)
+
+	// The planning meeting overlaps the long standup.
+	assert.Equal(t, 105*time.Minute, Total(meetings, from, from.AddDate(0, 0, 7)))
+	assert.Equal(t, 45*time.Minute, Total(meetings, from.Add(33*time.Hour), from.Add(34*time.Hour)))
+
+	current := At(meetings, time.Date(2018, 9, 4, 8, 45, 0, 0, time.UTC))
+	require.NotNil(t, current)
+	assert.Equal(t, "Long standup", current.Event.Summary)
+	current = At(meetings, time.Date(2018, 9, 4, 9, 20, 0, 0, time.UTC))
+	require.NotNil(t, current)
+	assert.Equal(t, "Planning", current.Event.Summary)
+	assert.Nil(t, At(meetings, time.Date(2018, 9, 4, 10, 0, 0, 0, time.UTC)))
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "calendar_test_dir")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	event := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:%s\nDTSTART:20180903T090000Z\nEND:VEVENT\nEND:VCALENDAR\n"
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "export", "work"), 0700))
+	for path, summary := range map[string]string{
+		"personal.ics":            "Dentist",
+		"export/work/standup.ics": "Standup",
+		"export/work/notes.txt":   "Not a calendar",
+	} {
+		contents := strings.Replace(event, "%s", summary, 1)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, path), []byte(contents), 0600))
+	}
+
+	events, err := Load([]string{filepath.Join(dir, "personal.ics"), filepath.Join(dir, "export")})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "Dentist", events[0].Summary)
+	assert.Equal(t, "Standup", events[1].Summary)
+	assert.False(t, LastModified([]string{dir}).IsZero())
+
+	_, err = Load([]string{filepath.Join(dir, "missing.ics")})
+	assert.Error(t, err)
+}
@@ -0,0 +1,368 @@
+package calendar
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A calendar event, or an override of a single occurrence of a recurring one.
+type Event struct {
+	UID     string
+	Summary string
+	Start   time.Time
+	// Events without an end take no time, unless they're all day.
+	Duration time.Duration
+	// All day events start at midnight in the local time zone.
+	AllDay bool
+
+	// Nil unless the event repeats.
+	Recurrence *Recurrence
+	// Occurrences of the recurrence that were removed.
+	Exceptions []time.Time
+	// Set on overrides, to the start of the occurrence they replace.
+	RecurrenceID time.Time
+
+	Attendees  int
+	Categories []string
+	// Transparent events don't make you busy, like reminders.
+	Transparent bool
+	Cancelled   bool
+}
+
+// Reports whether the event is a meeting: it takes time, doesn't leave you free, isn't
+// cancelled, and either has attendees or is in the Meeting category.
+func (e Event) Meeting() bool {
+	if e.AllDay || e.Duration <= 0 || e.Transparent || e.Cancelled {
+		return false
+	}
+	if e.Attendees > 0 {
+		return true
+	}
+	for _, category := range e.Categories {
+		if strings.EqualFold(category, "meeting") {
+			return true
+		}
+	}
+	return false
+}
+
+// A line of the file, with any continuation lines unfolded.
+type contentLine struct {
+	number int
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Reads the events of an iCalendar (RFC 5545) file. Only what's needed to know when
+// events happen and whether they're meetings is read. Events that can't be understood
+// are skipped, and the returned error says which, but the rest are still returned.
+func Parse(r io.Reader) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+	var events []Event
+	var problems []string
+	var components []string
+	// The properties of the event being read, nil outside of events.
+	var properties []contentLine
+	for _, line := range lines {
+		switch line.name {
+		case "BEGIN":
+			components = append(components, strings.ToUpper(line.value))
+			if strings.EqualFold(line.value, "VEVENT") {
+				properties = []contentLine{}
+			}
+		case "END":
+			if len(components) == 0 || components[len(components)-1] != strings.ToUpper(line.value) {
+				return events, fmt.Errorf("line %d: END:%s without a BEGIN", line.number, line.value)
+			}
+			components = components[:len(components)-1]
+			if strings.EqualFold(line.value, "VEVENT") {
+				event, err := parseEvent(properties)
+				if err != nil {
+					problems = append(problems, fmt.Sprintf("%q: %v", event.Summary, err))
+				} else {
+					events = append(events, event)
+				}
+				properties = nil
+			}
+		default:
+			// Properties of components inside of events, like alarms, don't matter.
+			if properties != nil && components[len(components)-1] == "VEVENT" {
+				properties = append(properties, line)
+			}
+		}
+	}
+	if len(components) > 0 {
+		return events, fmt.Errorf("BEGIN:%s is never ended", components[len(components)-1])
+	}
+	if len(problems) > 0 {
+		return events, fmt.Errorf("skipped %d events: %s", len(problems), strings.Join(problems, "; "))
+	}
+	return events, nil
+}
+
+// Properties can come in any order, but the end and the recurrence of an event are
+// interpreted in the time zone of its DTSTART, so that's read first.
+func parseEvent(properties []contentLine) (Event, error) {
+	event := Event{}
+	for _, line := range properties {
+		if line.name == "SUMMARY" {
+			event.Summary = unescape(line.value)
+		}
+	}
+	for _, line := range properties {
+		if line.name != "DTSTART" {
+			continue
+		}
+		var err error
+		event.Start, event.AllDay, err = parseTime(line.value, line.params)
+		if err != nil {
+			return event, fmt.Errorf("line %d: DTSTART: %v", line.number, err)
+		}
+		if event.AllDay {
+			event.Duration = 24 * time.Hour
+		}
+	}
+	if event.Start.IsZero() {
+		return event, errors.New("the event has no DTSTART")
+	}
+	for _, line := range properties {
+		if err := event.set(line); err != nil {
+			return event, fmt.Errorf("line %d: %s: %v", line.number, line.name, err)
+		}
+	}
+	return event, nil
+}
+
+// Sets whatever the property says about the event, other than its start and summary.
+func (e *Event) set(line contentLine) error {
+	var err error
+	switch line.name {
+	case "UID":
+		e.UID = line.value
+	case "DTEND":
+		var end time.Time
+		end, _, err = parseTime(line.value, line.params)
+		e.Duration = end.Sub(e.Start)
+	case "DURATION":
+		e.Duration, err = parseDuration(line.value)
+	case "RRULE":
+		e.Recurrence, err = parseRecurrence(line.value, e.Start.Location())
+	case "EXDATE":
+		for _, value := range strings.Split(line.value, ",") {
+			exception, _, err := parseTime(value, line.params)
+			if err != nil {
+				return err
+			}
+			e.Exceptions = append(e.Exceptions, exception)
+		}
+	case "RECURRENCE-ID":
+		e.RecurrenceID, _, err = parseTime(line.value, line.params)
+	case "ATTENDEE":
+		e.Attendees++
+	case "CATEGORIES":
+		for _, category := range splitText(line.value) {
+			e.Categories = append(e.Categories, strings.TrimSpace(category))
+		}
+	case "TRANSP":
+		e.Transparent = strings.EqualFold(line.value, "TRANSPARENT")
+	case "STATUS":
+		e.Cancelled = strings.EqualFold(line.value, "CANCELLED")
+	}
+	return err
+}
+
+func unfold(r io.Reader) ([]contentLine, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var raw []string
+	var numbers []int
+	number := 0
+	for scanner.Scan() {
+		number++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t")) && len(raw) > 0 {
+			raw[len(raw)-1] += text[1:]
+			continue
+		}
+		if text == "" {
+			continue
+		}
+		raw = append(raw, text)
+		numbers = append(numbers, number)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	lines := make([]contentLine, 0, len(raw))
+	for i, text := range raw {
+		line, err := parseLine(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", numbers[i], err)
+		}
+		line.number = numbers[i]
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// Splits `NAME;PARAM=value;PARAM="quoted:value":value` into its parts.
+func parseLine(text string) (contentLine, error) {
+	line := contentLine{params: make(map[string]string)}
+	end := strings.IndexAny(text, ";:")
+	if end <= 0 {
+		return line, errors.New("not a property")
+	}
+	line.name = strings.ToUpper(text[:end])
+	rest := text[end:]
+	for strings.HasPrefix(rest, ";") {
+		rest = rest[1:]
+		equals := strings.Index(rest, "=")
+		if equals == -1 {
+			return line, errors.New("a parameter has no value")
+		}
+		name := strings.ToUpper(rest[:equals])
+		rest = rest[equals+1:]
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			closing := strings.Index(rest[1:], `"`)
+			if closing == -1 {
+				return line, errors.New("a quoted parameter never ends")
+			}
+			value = rest[1 : closing+1]
+			rest = rest[closing+2:]
+		} else {
+			end := strings.IndexAny(rest, ";:")
+			if end == -1 {
+				return line, errors.New("the property has no value")
+			}
+			value = rest[:end]
+			rest = rest[end:]
+		}
+		line.params[name] = value
+	}
+	if !strings.HasPrefix(rest, ":") {
+		return line, errors.New("the property has no value")
+	}
+	line.value = rest[1:]
+	return line, nil
+}
+
+// Undoes the escaping of TEXT values.
+func unescape(value string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
+
+// Splits a list of TEXT values on the commas that aren't escaped.
+func splitText(value string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case ',':
+			parts = append(parts, unescape(value[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, unescape(value[start:]))
+}
+
+// Windows time zone names that show up as TZIDs in calendars exported by Outlook.
+var windowsZones = map[string]string{
+	"UTC":                            "UTC",
+	"GMT Standard Time":              "Europe/London",
+	"W. Europe Standard Time":        "Europe/Berlin",
+	"Romance Standard Time":          "Europe/Paris",
+	"Central Europe Standard Time":   "Europe/Budapest",
+	"E. Europe Standard Time":        "Europe/Chisinau",
+	"Eastern Standard Time":          "America/New_York",
+	"Central Standard Time":          "America/Chicago",
+	"Mountain Standard Time":         "America/Denver",
+	"Pacific Standard Time":          "America/Los_Angeles",
+	"India Standard Time":            "Asia/Kolkata",
+	"China Standard Time":            "Asia/Shanghai",
+	"Tokyo Standard Time":            "Asia/Tokyo",
+	"AUS Eastern Standard Time":      "Australia/Sydney",
+	"New Zealand Standard Time":      "Pacific/Auckland",
+	"E. South America Standard Time": "America/Sao_Paulo",
+}
+
+// Looks up a TZID. Besides IANA names like Europe/Berlin, this understands the Windows
+// names Outlook uses and the prefixed names some clients write, like
+// /mozilla.org/20050126_1/Europe/Berlin. Anything else is taken to be local time.
+func location(tzid string) *time.Location {
+	if name, ok := windowsZones[tzid]; ok {
+		tzid = name
+	}
+	parts := strings.Split(strings.Trim(tzid, "/"), "/")
+	for i := range parts {
+		if loc, err := time.LoadLocation(strings.Join(parts[i:], "/")); err == nil {
+			return loc
+		}
+	}
+	return time.Local
+}
+
+// Parses a DATE or DATE-TIME value. Times in UTC end in Z, other times are in their
+// TZID, or local time if they have none.
+func parseTime(value string, params map[string]string) (time.Time, bool, error) {
+	loc := time.Local
+	if tzid, ok := params["TZID"]; ok {
+		loc = location(tzid)
+	}
+	if params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", value, time.Local)
+		if err != nil {
+			return t, true, fmt.Errorf("%q is not a valid date", value)
+		}
+		return t, true, nil
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		if err != nil {
+			return t, false, fmt.Errorf("%q is not a valid time", value)
+		}
+		return t, false, nil
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	if err != nil {
+		return t, false, fmt.Errorf("%q is not a valid time", value)
+	}
+	return t, false, nil
+}
+
+var durationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// Parses a DURATION value like PT1H30M or P1D.
+func parseDuration(value string) (time.Duration, error) {
+	match := durationPattern.FindStringSubmatch(value)
+	if match == nil || value == "P" || strings.HasSuffix(value, "T") {
+		return 0, fmt.Errorf("%q is not a valid duration", value)
+	}
+	var d time.Duration
+	for i, unit := range []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if match[i+2] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(match[i+2])
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a valid duration", value)
+		}
+		d += time.Duration(n) * unit
+	}
+	if match[1] == "-" {
+		d = -d
+	}
+	return d, nil
+}
@@ -0,0 +1,296 @@
+package calendar
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// When a recurring event repeats, from its RRULE. BYSETPOS, BYWEEKNO, BYYEARDAY and
+// anything more frequent than daily aren't supported.
+type Recurrence struct {
+	// DAILY, WEEKLY, MONTHLY or YEARLY.
+	Frequency string
+	// Every how many days, weeks, months or years the event repeats, at least 1.
+	Interval int
+	// How many occurrences there are in total, zero for no limit.
+	Count int
+	// When the last occurrence starts at the latest, zero for no limit.
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+}
+
+// A day of the week, optionally the N-th one of the month or year, counting from the end
+// if N is negative.
+type WeekdayNum struct {
+	N       int
+	Weekday time.Weekday
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// Parses an RRULE value like FREQ=WEEKLY;BYDAY=MO,WE. A floating UNTIL is in loc, the
+// time zone of the event's start.
+func parseRecurrence(value string, loc *time.Location) (*Recurrence, error) {
+	r := &Recurrence{Interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		keyValue := strings.SplitN(part, "=", 2)
+		if len(keyValue) != 2 {
+			return nil, fmt.Errorf("%q is not a KEY=VALUE pair", part)
+		}
+		key, v := strings.ToUpper(keyValue[0]), keyValue[1]
+		var err error
+		switch key {
+		case "FREQ":
+			r.Frequency = strings.ToUpper(v)
+			switch r.Frequency {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+			default:
+				return nil, fmt.Errorf("FREQ=%s isn't supported", v)
+			}
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(v)
+			if err == nil && r.Interval < 1 {
+				err = fmt.Errorf("INTERVAL has to be at least 1")
+			}
+		case "COUNT":
+			r.Count, err = strconv.Atoi(v)
+			if err == nil && r.Count < 1 {
+				err = fmt.Errorf("COUNT has to be at least 1")
+			}
+		case "UNTIL":
+			params := map[string]string{}
+			if !strings.HasSuffix(v, "Z") {
+				params["TZID"] = loc.String()
+			}
+			var allDay bool
+			r.Until, allDay, err = parseTime(v, params)
+			if err == nil && allDay {
+				// The whole last day counts.
+				r.Until = r.Until.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			}
+		case "BYDAY":
+			for _, day := range strings.Split(v, ",") {
+				day = strings.ToUpper(strings.TrimSpace(day))
+				if len(day) < 2 {
+					return nil, fmt.Errorf("%q is not a day", day)
+				}
+				weekday, ok := weekdays[day[len(day)-2:]]
+				if !ok {
+					return nil, fmt.Errorf("%q is not a day", day)
+				}
+				n := 0
+				if prefix := day[:len(day)-2]; prefix != "" {
+					n, err = strconv.Atoi(prefix)
+					if err != nil || n == 0 {
+						return nil, fmt.Errorf("%q is not a day", day)
+					}
+				}
+				r.ByDay = append(r.ByDay, WeekdayNum{N: n, Weekday: weekday})
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(v, ",") {
+				n, err := strconv.Atoi(day)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("%q is not a day of the month", day)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+		case "BYMONTH":
+			for _, month := range strings.Split(v, ",") {
+				n, err := strconv.Atoi(month)
+				if err != nil || n < 1 || n > 12 {
+					return nil, fmt.Errorf("%q is not a month", month)
+				}
+				r.ByMonth = append(r.ByMonth, time.Month(n))
+			}
+		case "WKST":
+			// Weeks always start on Monday, which only matters for weekly events with an
+			// interval and days that straddle Monday.
+		default:
+			return nil, fmt.Errorf("%s isn't supported", key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if r.Frequency == "" {
+		return nil, fmt.Errorf("FREQ is required")
+	}
+	return r, nil
+}
+
+// A day without a time of day or time zone, kept in UTC so days are always 24 hours.
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// Calls yield with the start of every occurrence of an event that starts at start, in
+// order, until yield returns false or the occurrences are past end. Occurrences have the
+// same wall clock time as start in its time zone, whatever the daylight saving time.
+func (r *Recurrence) each(start, end time.Time, yield func(time.Time) bool) {
+	year, month, day := start.Date()
+	hour, minute, second := start.Clock()
+	at := func(d time.Time) time.Time {
+		return time.Date(d.Year(), d.Month(), d.Day(), hour, minute, second, start.Nanosecond(), start.Location())
+	}
+	count := 0
+	for period := 0; ; period += r.Interval {
+		var days []time.Time
+		var periodStart time.Time
+		switch r.Frequency {
+		case "DAILY":
+			periodStart = date(year, month, day+period)
+			if r.matchesMonth(periodStart) && r.matchesMonthDay(periodStart) && r.matchesWeekday(periodStart) {
+				days = []time.Time{periodStart}
+			}
+		case "WEEKLY":
+			// Weeks start on Monday.
+			periodStart = date(year, month, day-(int(start.Weekday())+6)%7+7*period)
+			weekdays := []WeekdayNum{{Weekday: start.Weekday()}}
+			if len(r.ByDay) > 0 {
+				weekdays = r.ByDay
+			}
+			for _, weekday := range weekdays {
+				d := periodStart.AddDate(0, 0, (int(weekday.Weekday)+6)%7)
+				if r.matchesMonth(d) {
+					days = append(days, d)
+				}
+			}
+		case "MONTHLY":
+			periodStart = date(year, month+time.Month(period), 1)
+			if r.matchesMonth(periodStart) {
+				days = r.inMonth(periodStart, day)
+			}
+		case "YEARLY":
+			periodStart = date(year+period, 1, 1)
+			if len(r.ByMonth) == 0 && len(r.ByMonthDay) == 0 && len(r.ByDay) > 0 {
+				days = r.byDay(periodStart, periodStart.AddDate(1, 0, -1))
+				break
+			}
+			months := r.ByMonth
+			if len(months) == 0 {
+				months = []time.Month{month}
+			}
+			for _, m := range months {
+				days = append(days, r.inMonth(date(year+period, m, 1), day)...)
+			}
+		}
+		if at(periodStart).After(end) {
+			return
+		}
+		sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+		for i, d := range days {
+			if i > 0 && d.Equal(days[i-1]) {
+				continue
+			}
+			t := at(d)
+			if t.Before(start) {
+				continue
+			}
+			count++
+			if (r.Count > 0 && count > r.Count) || (!r.Until.IsZero() && t.After(r.Until)) {
+				return
+			}
+			if !yield(t) {
+				return
+			}
+		}
+	}
+}
+
+// Returns the days of the month that starts at first that the event happens on, where
+// day is the day of the month of the event's start.
+func (r *Recurrence) inMonth(first time.Time, day int) []time.Time {
+	last := first.AddDate(0, 1, -1)
+	var days []time.Time
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, n := range r.ByMonthDay {
+			if n < 0 {
+				n = last.Day() + n + 1
+			}
+			if n < 1 || n > last.Day() {
+				continue
+			}
+			d := date(first.Year(), first.Month(), n)
+			// Days of the week narrow days of the month down.
+			if r.matchesWeekday(d) {
+				days = append(days, d)
+			}
+		}
+	case len(r.ByDay) > 0:
+		days = r.byDay(first, last)
+	case day <= last.Day():
+		// Months that are too short for the day are skipped.
+		days = []time.Time{date(first.Year(), first.Month(), day)}
+	}
+	return days
+}
+
+// Returns the days from first to last that are on one of the ByDay days of the week,
+// picking the N-th one of the range if there's an N.
+func (r *Recurrence) byDay(first, last time.Time) []time.Time {
+	var days []time.Time
+	for _, weekday := range r.ByDay {
+		var matches []time.Time
+		for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+			if d.Weekday() == weekday.Weekday {
+				matches = append(matches, d)
+			}
+		}
+		switch {
+		case weekday.N == 0:
+			days = append(days, matches...)
+		case weekday.N > 0 && weekday.N <= len(matches):
+			days = append(days, matches[weekday.N-1])
+		case weekday.N < 0 && -weekday.N <= len(matches):
+			days = append(days, matches[len(matches)+weekday.N])
+		}
+	}
+	return days
+}
+
+func (r *Recurrence) matchesMonth(d time.Time) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, month := range r.ByMonth {
+		if d.Month() == month {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Recurrence) matchesMonthDay(d time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	last := date(d.Year(), d.Month()+1, 0).Day()
+	for _, n := range r.ByMonthDay {
+		if n == d.Day() || last+n+1 == d.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Recurrence) matchesWeekday(d time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, weekday := range r.ByDay {
+		if d.Weekday() == weekday.Weekday {
+			return true
+		}
+	}
+	return false
+}
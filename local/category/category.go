@@ -0,0 +1,169 @@
+package category
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dwetterau/glider/local/enforce"
+	"github.com/dwetterau/glider/local/track"
+	"github.com/dwetterau/glider/local/xscan"
+)
+
+const (
+	Productive  = "productive"
+	Neutral     = "neutral"
+	Distracting = "distracting"
+)
+
+// Reports whether name is one of the categories.
+func Valid(name string) bool {
+	return name == Productive || name == Neutral || name == Distracting
+}
+
+type Config struct {
+	// Application name patterns for each category, matched like the enforce allowlist.
+	// Terminals and editors are productive unless they're listed elsewhere, and every
+	// other application is neutral.
+	Applications map[string][]string
+	// When to send the end of day summary, as the local time since midnight. Negative
+	// means never.
+	EndOfDay time.Duration
+	// The names of the notifiers to send the summary to. Defaults to the default
+	// notifier of the annoyer rules, or every notifier if that isn't configured.
+	Notifiers []string
+}
+
+func DefaultConfig() Config {
+	return Config{EndOfDay: 18 * time.Hour}
+}
+
+// Puts tracked time into categories. Time that matched an annoyer rule goes into the
+// rule's category, everything else into its application's.
+type Categorizer struct {
+	applications map[string][]string
+	rules        map[string]string
+}
+
+// Rules maps rule names to their category, where an empty category means distracting.
+func NewCategorizer(c Config, rules map[string]string) *Categorizer {
+	return &Categorizer{applications: c.Applications, rules: rules}
+}
+
+func (c *Categorizer) Categorize(application, rule string) string {
+	if category, ok := c.rules[rule]; ok && rule != "" {
+		if category == "" {
+			return Distracting
+		}
+		return category
+	}
+	name := strings.ToLower(application)
+	for _, category := range []string{Distracting, Productive, Neutral} {
+		for _, pattern := range c.applications[category] {
+			if matched, _ := path.Match(strings.ToLower(pattern), name); matched {
+				return category
+			}
+		}
+	}
+	if enforce.OnAllowlist(nil, xscan.Window{ApplicationName: application}) && application != "" {
+		return Productive
+	}
+	return Neutral
+}
+
+// How a stretch of time went.
+type Summary struct {
+	// From 0 to 100, see Summarize.
+	Score int
+	// The time spent in each category.
+	Totals map[string]time.Duration
+	// The applications with the most distracting time, most first.
+	Distractions []track.Total
+	// The time spent on each project, most first.
+	Projects []Project
+}
+
+// How much time went into a project, and how much of that was productive.
+type Project struct {
+	Name              string
+	Total, Productive time.Duration
+}
+
+// Totals the usage by category. The score is the share of the time that was productive,
+// with neutral time counting half. Idle time doesn't count at all, and without any time
+// the score is 0.
+func (c *Categorizer) Summarize(usage []track.Usage) Summary {
+	summary := Summary{Totals: make(map[string]time.Duration)}
+	distractions := make(map[string]time.Duration)
+	projects := make(map[string]*Project)
+	var total time.Duration
+	for _, u := range usage {
+		if u.Application == xscan.IdleWindow.ApplicationName || u.Application == "" {
+			continue
+		}
+		category := c.Categorize(u.Application, u.Rule)
+		summary.Totals[category] += u.Duration
+		total += u.Duration
+		if category == Distracting {
+			distractions[u.Application] += u.Duration
+		}
+		if u.Project != "" {
+			p, ok := projects[u.Project]
+			if !ok {
+				p = &Project{Name: u.Project}
+				projects[u.Project] = p
+			}
+			p.Total += u.Duration
+			if category == Productive {
+				p.Productive += u.Duration
+			}
+		}
+	}
+	if total > 0 {
+		weighted := float64(summary.Totals[Productive]) + float64(summary.Totals[Neutral])/2
+		summary.Score = int(math.Round(100 * weighted / float64(total)))
+	}
+	for application, duration := range distractions {
+		summary.Distractions = append(summary.Distractions, track.Total{Name: application, Duration: duration})
+	}
+	sort.Slice(summary.Distractions, func(i, j int) bool {
+		if summary.Distractions[i].Duration != summary.Distractions[j].Duration {
+			return summary.Distractions[i].Duration > summary.Distractions[j].Duration
+		}
+		return summary.Distractions[i].Name < summary.Distractions[j].Name
+	})
+	for _, p := range projects {
+		summary.Projects = append(summary.Projects, *p)
+	}
+	sort.Slice(summary.Projects, func(i, j int) bool {
+		if summary.Projects[i].Total != summary.Projects[j].Total {
+			return summary.Projects[i].Total > summary.Projects[j].Total
+		}
+		return summary.Projects[i].Name < summary.Projects[j].Name
+	})
+	return summary
+}
+
+// Describes the day for the end of day notification.
+func (s Summary) Message() string {
+	message := fmt.Sprintf(
+		"Today's focus score is %d: %v productive, %v neutral and %v distracting.",
+		s.Score, s.Totals[Productive].Round(time.Minute), s.Totals[Neutral].Round(time.Minute),
+		s.Totals[Distracting].Round(time.Minute),
+	)
+	if len(s.Distractions) > 0 {
+		top := s.Distractions
+		if len(top) > 3 {
+			top = top[:3]
+		}
+		parts := make([]string, len(top))
+		for i, d := range top {
+			parts[i] = fmt.Sprintf("%s %v", d.Name, d.Duration.Round(time.Minute))
+		}
+		message += " Most distracting: " + strings.Join(parts, ", ") + "."
+	}
+	return message
+}
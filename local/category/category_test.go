@@ -0,0 +1,61 @@
+package category
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dwetterau/glider/local/track"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCategorize(t *testing.T) {
+	c := NewCategorizer(Config{Applications: map[string][]string{
+		Productive:  {"zathura"},
+		Distracting: {"discord", "alacritty"},
+	}}, map[string]string{"slack": "", "docs": Productive})
+
+	assert.Equal(t, Distracting, c.Categorize("Slack", "slack"))
+	assert.Equal(t, Productive, c.Categorize("firefox", "docs"))
+	assert.Equal(t, Productive, c.Categorize("Zathura", ""))
+	assert.Equal(t, Distracting, c.Categorize("Discord", ""))
+	// Terminals and editors are productive, unless they're listed elsewhere.
+	assert.Equal(t, Productive, c.Categorize("Code", ""))
+	assert.Equal(t, Distracting, c.Categorize("Alacritty", ""))
+	assert.Equal(t, Neutral, c.Categorize("Thunderbird", ""))
+}
+
+func TestSummarize(t *testing.T) {
+	c := NewCategorizer(Config{}, map[string]string{"slack": "", "youtube": ""})
+	summary := c.Summarize([]track.Usage{
+		{Application: "Code", Project: "glider", Duration: 2 * time.Hour},
+		{Application: "Code", Project: "wit", Duration: time.Hour},
+		{Application: "Thunderbird", Duration: time.Hour},
+		{Application: "Slack", Rule: "slack", Duration: 30 * time.Minute},
+		{Application: "firefox", Rule: "youtube", Duration: 30 * time.Minute},
+		{Application: "firefox", Project: "wit", Duration: time.Hour},
+		{Application: "idle", Duration: 5 * time.Hour},
+	})
+	// (3h + 2h / 2) / 6h
+	assert.Equal(t, 67, summary.Score)
+	assert.Equal(t, map[string]time.Duration{
+		Productive:  3 * time.Hour,
+		Neutral:     2 * time.Hour,
+		Distracting: time.Hour,
+	}, summary.Totals)
+	assert.Equal(t, []track.Total{
+		{Name: "Slack", Duration: 30 * time.Minute},
+		{Name: "firefox", Duration: 30 * time.Minute},
+	}, summary.Distractions)
+	assert.Equal(t, []Project{
+		{Name: "glider", Total: 2 * time.Hour, Productive: 2 * time.Hour},
+		{Name: "wit", Total: 2 * time.Hour, Productive: time.Hour},
+	}, summary.Projects)
+	assert.Equal(
+		t,
+		"Today's focus score is 67: 3h0m0s productive, 2h0m0s neutral and 1h0m0s distracting. "+
+			"Most distracting: Slack 30m0s, firefox 30m0s.",
+		summary.Message(),
+	)
+
+	assert.Equal(t, 0, c.Summarize(nil).Score)
+}
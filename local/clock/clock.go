@@ -0,0 +1,47 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Tells the time. Anything that depends on the time of day takes a Clock so that tests
+// can control it.
+type Clock interface {
+	Now() time.Time
+}
+
+// The wall clock.
+type Real struct{}
+
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// A clock that only moves when it's told to.
+type Fake struct {
+	lock sync.Mutex
+	now  time.Time
+}
+
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+func (f *Fake) Now() time.Time {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.now
+}
+
+func (f *Fake) Set(now time.Time) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.now = now
+}
+
+func (f *Fake) Advance(d time.Duration) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.now = f.now.Add(d)
+}
@@ -0,0 +1,679 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/dwetterau/glider/local/annoy"
+	"github.com/dwetterau/glider/local/category"
+	"github.com/dwetterau/glider/local/enforce"
+	"github.com/dwetterau/glider/local/focus"
+	"github.com/dwetterau/glider/local/notify"
+	"github.com/dwetterau/glider/local/project"
+	"github.com/dwetterau/glider/local/schedule"
+	"github.com/dwetterau/glider/local/task"
+	"github.com/dwetterau/glider/local/thrash"
+	"github.com/dwetterau/glider/local/tool"
+)
+
+// Everything the local daemon can be configured with.
+type Config struct {
+	Rules      []annoy.Rule
+	Notifiers  map[string]notify.Config
+	Enforce    enforce.Config
+	Thrashing  thrash.Config
+	Focus      focus.Config
+	Browser    Browser
+	Categories category.Config
+	Calendar   Calendar
+	Tasks      task.Config
+	Projects   project.Config
+	// Named sets of rules that can take the place of Rules, e.g. during meetings.
+	Profiles map[string][]annoy.Rule
+}
+
+// Where meetings come from and what happens during them.
+type Calendar struct {
+	// .ics files, or directories of them like a CalDAV export.
+	Paths []string
+	// The profile whose rules are used during meetings, or empty to suspend every rule.
+	Profile string
+}
+
+// Where browser windows' domains come from.
+type Browser struct {
+	// Site names to look for in page titles, extending browser.DefaultSites.
+	Sites map[string]string
+	// The loopback address to listen on for a browser extension, empty to not listen.
+	Listen string
+	// The bearer token the extension has to send, if not empty.
+	Token string
+}
+
+// The config that's used when there's no config file.
+func Default() *Config {
+	return &Config{
+		Rules:      annoy.DefaultRules(),
+		Notifiers:  defaultNotifiers(),
+		Thrashing:  defaultThrashing(),
+		Focus:      focus.DefaultConfig(),
+		Categories: category.DefaultConfig(),
+	}
+}
+
+// Switches are always counted for the status, but thrashing alerts are only sent if
+// they're asked for.
+func defaultThrashing() thrash.Config {
+	c := thrash.DefaultConfig()
+	c.Threshold = 0
+	return c
+}
+
+func defaultNotifiers() map[string]notify.Config {
+	return map[string]notify.Config{annoy.DefaultNotifier: {Type: notify.TypeDBus}}
+}
+
+// Returns $XDG_CONFIG_HOME/glider/config.toml, falling back to ~/.config.
+func DefaultPath() string {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+	return filepath.Join(configHome, "glider", "config.toml")
+}
+
+// Reads and validates the config file at path. Validation errors point at the offending
+// rule, e.g. `config.toml: rule 2 ("gmail"): bucket_size must be positive`.
+func Load(path string) (*Config, error) {
+	var raw rawConfig
+	metadata, err := toml.DecodeFile(path, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, len(undecoded))
+		for i, key := range undecoded {
+			keys[i] = key.String()
+		}
+		return nil, fmt.Errorf("%s: unknown keys %s", path, strings.Join(keys, ", "))
+	}
+	c, err := raw.parse()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return c, nil
+}
+
+// Loads the config at path, or the default config if path is the default path and
+// there's nothing there yet.
+func LoadOrDefault(path string) (*Config, error) {
+	if path == DefaultPath() {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return Default(), nil
+		}
+	}
+	return Load(path)
+}
+
+type rawConfig struct {
+	Rules      []rawRule              `toml:"rule"`
+	Notifiers  map[string]rawNotifier `toml:"notifier"`
+	Enforce    rawEnforce             `toml:"enforce"`
+	Thrashing  *rawThrashing          `toml:"thrashing"`
+	Focus      rawFocus               `toml:"focus"`
+	Browser    rawBrowser             `toml:"browser"`
+	Categories rawCategories          `toml:"categories"`
+	Calendar   rawCalendar            `toml:"calendar"`
+	Tasks      rawTasks               `toml:"tasks"`
+	Projects   rawProjects            `toml:"projects"`
+	Profiles   map[string]rawProfile  `toml:"profile"`
+}
+
+type rawCalendar struct {
+	Paths          []string `toml:"paths"`
+	DuringMeetings string   `toml:"during_meetings"`
+}
+
+type rawTasks struct {
+	TodoTxt     string              `toml:"todo_txt"`
+	Taskwarrior string              `toml:"taskwarrior"`
+	Contexts    map[string][]string `toml:"contexts"`
+}
+
+type rawProjects struct {
+	Roots      []string       `toml:"roots"`
+	Extractors []rawExtractor `toml:"extractor"`
+}
+
+type rawExtractor struct {
+	Application string `toml:"application"`
+	Title       string `toml:"title"`
+}
+
+type rawProfile struct {
+	Rules []rawRule `toml:"rule"`
+}
+
+type rawCategories struct {
+	Productive  []string `toml:"productive"`
+	Neutral     []string `toml:"neutral"`
+	Distracting []string `toml:"distracting"`
+	EndOfDay    string   `toml:"end_of_day"`
+	Notifiers   []string `toml:"notifiers"`
+}
+
+type rawBrowser struct {
+	Sites  map[string]string `toml:"sites"`
+	Listen string            `toml:"listen"`
+	Token  string            `toml:"token"`
+}
+
+type rawFocus struct {
+	Allowlist []string `toml:"allowlist"`
+	Break     *string  `toml:"break"`
+	Repeat    string   `toml:"repeat"`
+	Notifiers []string `toml:"notifiers"`
+}
+
+type rawEnforce struct {
+	DryRun    bool     `toml:"dry_run"`
+	Allowlist []string `toml:"allowlist"`
+	WorkClass string   `toml:"work_class"`
+}
+
+type rawThrashing struct {
+	SwitchesPerMinute *float64 `toml:"switches_per_minute"`
+	Window            string   `toml:"window"`
+	Cooldown          string   `toml:"cooldown"`
+	Notifiers         []string `toml:"notifiers"`
+}
+
+type rawRule struct {
+	Name        string     `toml:"name"`
+	Application string     `toml:"application"`
+	Title       string     `toml:"title"`
+	Executable  string     `toml:"executable"`
+	Domain      string     `toml:"domain"`
+	BucketSize  string     `toml:"bucket_size"`
+	DrainFactor *float64   `toml:"drain_factor"`
+	Message     string     `toml:"message"`
+	Notifiers   []string   `toml:"notifiers"`
+	Escalation  []rawStep  `toml:"escalation"`
+	Repeat      string     `toml:"repeat"`
+	Schedule    []string   `toml:"schedule"`
+	Budget      *rawBudget `toml:"budget"`
+	Category    string     `toml:"category"`
+}
+
+type rawBudget struct {
+	Daily    string    `toml:"daily"`
+	Reset    string    `toml:"reset"`
+	Warnings []float64 `toml:"warnings"`
+}
+
+type rawStep struct {
+	After   string `toml:"after"`
+	Urgency string `toml:"urgency"`
+	Message string `toml:"message"`
+	Action  string `toml:"action"`
+	Target  string `toml:"target"`
+	DryRun  bool   `toml:"dry_run"`
+}
+
+type rawNotifier struct {
+	Type    string `toml:"type"`
+	URL     string `toml:"url"`
+	Timeout string `toml:"timeout"`
+}
+
+// Durations are written as strings like "3m" or "1h30m". They're parsed after decoding
+// so that errors can point at the rule they're in.
+func parseDuration(field, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not a valid duration: %v", field, err)
+	}
+	return d, nil
+}
+
+func (raw rawConfig) parse() (*Config, error) {
+	c := &Config{
+		Notifiers: make(map[string]notify.Config, len(raw.Notifiers)),
+		Enforce: enforce.Config{
+			DryRun:    raw.Enforce.DryRun,
+			Allowlist: raw.Enforce.Allowlist,
+			WorkClass: raw.Enforce.WorkClass,
+		},
+	}
+	for name, n := range raw.Notifiers {
+		notifier, err := n.parse()
+		if err != nil {
+			return nil, fmt.Errorf("notifier %q: %v", name, err)
+		}
+		c.Notifiers[name] = notifier
+	}
+	var err error
+	c.Thrashing = defaultThrashing()
+	if raw.Thrashing != nil {
+		c.Thrashing, err = raw.Thrashing.parse()
+		if err == nil && c.Thrashing.Threshold > 0 {
+			err = c.checkNotifiers(c.Thrashing.Notifiers)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("thrashing: %v", err)
+		}
+	}
+	c.Focus, err = raw.Focus.parse()
+	// Focus sessions without notifiers of their own use whatever the rules use.
+	if err == nil && len(c.Focus.Notifiers) > 0 {
+		err = c.checkNotifiers(c.Focus.Notifiers)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("focus: %v", err)
+	}
+	c.Browser, err = raw.Browser.parse()
+	if err != nil {
+		return nil, fmt.Errorf("browser: %v", err)
+	}
+	c.Categories, err = raw.Categories.parse()
+	// Like focus sessions, the summary falls back to whatever the rules use.
+	if err == nil && len(c.Categories.Notifiers) > 0 {
+		err = c.checkNotifiers(c.Categories.Notifiers)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("categories: %v", err)
+	}
+	if len(raw.Rules) == 0 {
+		return nil, errors.New("no rules defined, add at least one [[rule]]")
+	}
+	c.Rules, err = c.parseRules(raw.Rules)
+	if err != nil {
+		return nil, err
+	}
+	for name, profile := range raw.Profiles {
+		rules, err := c.parseRules(profile.Rules)
+		if err != nil {
+			return nil, fmt.Errorf("profile %q: %v", name, err)
+		}
+		if c.Profiles == nil {
+			c.Profiles = make(map[string][]annoy.Rule, len(raw.Profiles))
+		}
+		c.Profiles[name] = rules
+	}
+	c.Calendar, err = raw.Calendar.parse(c.Profiles)
+	if err != nil {
+		return nil, fmt.Errorf("calendar: %v", err)
+	}
+	c.Tasks, err = raw.Tasks.parse()
+	if err != nil {
+		return nil, fmt.Errorf("tasks: %v", err)
+	}
+	c.Projects, err = raw.Projects.parse()
+	if err != nil {
+		return nil, fmt.Errorf("projects: %v", err)
+	}
+	return c, nil
+}
+
+func (c *Config) parseRules(raws []rawRule) ([]annoy.Rule, error) {
+	var rules []annoy.Rule
+	seen := make(map[string]struct{}, len(raws))
+	for i, r := range raws {
+		rule, err := r.parse(c.Enforce)
+		if err == nil {
+			err = c.checkNotifiers(rule.Notifiers)
+		}
+		if err == nil {
+			if _, ok := seen[rule.Name]; ok {
+				err = errors.New("another rule already has this name")
+			}
+			seen[rule.Name] = struct{}{}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("rule %d (%q): %v", i+1, r.Name, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Makes sure every named notifier exists. No names means the default desktop notifier,
+// which only has to be configured to change its settings.
+func (c *Config) checkNotifiers(names []string) error {
+	if len(names) == 0 {
+		if _, ok := c.Notifiers[annoy.DefaultNotifier]; !ok {
+			c.Notifiers[annoy.DefaultNotifier] = defaultNotifiers()[annoy.DefaultNotifier]
+		}
+	}
+	for _, name := range names {
+		if _, ok := c.Notifiers[name]; !ok {
+			return fmt.Errorf("there's no [notifier.%s]", name)
+		}
+	}
+	return nil
+}
+
+func (t rawThrashing) parse() (thrash.Config, error) {
+	c := thrash.DefaultConfig()
+	if t.SwitchesPerMinute != nil {
+		c.Threshold = *t.SwitchesPerMinute
+	}
+	if c.Threshold < 0 {
+		return c, errors.New("switches_per_minute can't be negative")
+	}
+	if t.Window != "" {
+		window, err := parseDuration("window", t.Window)
+		if err != nil {
+			return c, err
+		}
+		if window < time.Minute {
+			return c, errors.New("window has to be at least a minute")
+		}
+		c.Window = window
+	}
+	if t.Cooldown != "" {
+		cooldown, err := parseDuration("cooldown", t.Cooldown)
+		if err != nil {
+			return c, err
+		}
+		if cooldown < 0 {
+			return c, errors.New("cooldown can't be negative")
+		}
+		c.Cooldown = cooldown
+	}
+	c.Notifiers = t.Notifiers
+	return c, nil
+}
+
+func (f rawFocus) parse() (focus.Config, error) {
+	c := focus.DefaultConfig()
+	c.Allowlist = f.Allowlist
+	c.Notifiers = f.Notifiers
+	if f.Break != nil {
+		var err error
+		c.Break, err = parseDuration("break", *f.Break)
+		if err != nil {
+			return c, err
+		}
+		if c.Break < 0 {
+			return c, errors.New("break can't be negative")
+		}
+	}
+	if f.Repeat != "" {
+		var err error
+		c.Repeat, err = parseDuration("repeat", f.Repeat)
+		if err != nil {
+			return c, err
+		}
+		if c.Repeat <= 0 {
+			return c, errors.New("repeat must be positive")
+		}
+	}
+	return c, nil
+}
+
+func (b rawBrowser) parse() (Browser, error) {
+	c := Browser{Sites: b.Sites, Listen: b.Listen, Token: b.Token}
+	for name, domain := range c.Sites {
+		if name == "" || domain == "" || strings.Contains(domain, "/") {
+			return c, fmt.Errorf("site %q should map a name in page titles to a bare domain", name)
+		}
+	}
+	if c.Token != "" && c.Listen == "" {
+		return c, errors.New("token only makes sense with listen")
+	}
+	return c, nil
+}
+
+func (r rawCategories) parse() (category.Config, error) {
+	c := category.DefaultConfig()
+	c.Notifiers = r.Notifiers
+	c.Applications = map[string][]string{
+		category.Productive:  r.Productive,
+		category.Neutral:     r.Neutral,
+		category.Distracting: r.Distracting,
+	}
+	switch r.EndOfDay {
+	case "":
+	case "off":
+		c.EndOfDay = -1
+	default:
+		endOfDay, err := time.Parse("15:04", r.EndOfDay)
+		if err != nil {
+			return c, errors.New("end_of_day is not a valid time of day, use HH:MM or off")
+		}
+		c.EndOfDay = time.Duration(endOfDay.Hour())*time.Hour + time.Duration(endOfDay.Minute())*time.Minute
+	}
+	return c, nil
+}
+
+func (r rawCalendar) parse(profiles map[string][]annoy.Rule) (Calendar, error) {
+	c := Calendar{}
+	for _, path := range r.Paths {
+		c.Paths = append(c.Paths, tool.ExpandHome(path))
+	}
+	if r.DuringMeetings != "" && r.DuringMeetings != "suspend" {
+		if _, ok := profiles[r.DuringMeetings]; !ok {
+			return c, fmt.Errorf("during_meetings should be suspend or a profile, there's no [[profile.%s.rule]]", r.DuringMeetings)
+		}
+		c.Profile = r.DuringMeetings
+	}
+	return c, nil
+}
+
+func (r rawTasks) parse() (task.Config, error) {
+	c := task.Config{TodoTxt: tool.ExpandHome(r.TodoTxt), Taskwarrior: tool.ExpandHome(r.Taskwarrior)}
+	for context, expressions := range r.Contexts {
+		s, err := schedule.Parse(expressions)
+		if err != nil {
+			return c, fmt.Errorf("context %q: %v", context, err)
+		}
+		if c.Contexts == nil {
+			c.Contexts = make(map[string]schedule.Schedule, len(r.Contexts))
+		}
+		c.Contexts[context] = s
+	}
+	return c, nil
+}
+
+func (r rawProjects) parse() (project.Config, error) {
+	c := project.Config{}
+	for _, root := range r.Roots {
+		c.Roots = append(c.Roots, tool.ExpandHome(root))
+	}
+	for i, raw := range r.Extractors {
+		e, err := raw.parse()
+		if err != nil {
+			return c, fmt.Errorf("extractor %d: %v", i+1, err)
+		}
+		c.Extractors = append(c.Extractors, e)
+	}
+	return c, nil
+}
+
+func (r rawExtractor) parse() (project.Extractor, error) {
+	e := project.Extractor{}
+	var err error
+	// An empty application matches every window.
+	e.Application, err = regexp.Compile(r.Application)
+	if err != nil {
+		return e, fmt.Errorf("application is not a valid regex: %v", err)
+	}
+	if r.Title == "" {
+		return e, errors.New("title is required")
+	}
+	e.Title, err = regexp.Compile(r.Title)
+	if err != nil {
+		return e, fmt.Errorf("title is not a valid regex: %v", err)
+	}
+	if e.Title.NumSubexp() == 0 {
+		return e, errors.New("title needs a group that captures the project")
+	}
+	return e, nil
+}
+
+func (n rawNotifier) parse() (notify.Config, error) {
+	c := notify.Config{Type: n.Type, URL: n.URL}
+	var err error
+	c.Timeout, err = parseDuration("timeout", n.Timeout)
+	if err != nil {
+		return c, err
+	}
+	switch c.Type {
+	case notify.TypeDBus, notify.TypeTerminal:
+	case notify.TypeWebhook:
+		if c.URL == "" {
+			return c, errors.New("url is required for webhooks")
+		}
+	case "":
+		return c, errors.New("type is required")
+	default:
+		return c, fmt.Errorf("unknown type %q, use dbus, terminal or webhook", c.Type)
+	}
+	return c, nil
+}
+
+func (r rawRule) parse(e enforce.Config) (annoy.Rule, error) {
+	rule := annoy.Rule{
+		Name:            r.Name,
+		ApplicationName: r.Application,
+		Executable:      r.Executable,
+		Domain:          strings.ToLower(strings.TrimPrefix(r.Domain, "www.")),
+		DrainFactor:     1,
+		Message:         r.Message,
+		Notifiers:       r.Notifiers,
+		Category:        r.Category,
+	}
+	if rule.Name == "" {
+		return rule, errors.New("name is required")
+	}
+	if r.Title != "" {
+		title, err := regexp.Compile(r.Title)
+		if err != nil {
+			return rule, fmt.Errorf("title is not a valid regex: %v", err)
+		}
+		rule.Title = title
+	}
+	if rule.ApplicationName == "" && rule.Title == nil && rule.Executable == "" && rule.Domain == "" {
+		return rule, errors.New("at least one of application, title, executable or domain is required")
+	}
+	if strings.Contains(rule.Domain, "/") {
+		return rule, errors.New("domain should be a bare domain like youtube.com, not a URL")
+	}
+	var err error
+	rule.BucketSize, err = parseDuration("bucket_size", r.BucketSize)
+	if err != nil {
+		return rule, err
+	}
+	if rule.BucketSize < 0 {
+		return rule, errors.New("bucket_size must be positive")
+	}
+	if rule.BucketSize == 0 && r.Budget == nil {
+		return rule, errors.New("bucket_size must be positive, unless the rule has a budget")
+	}
+	if r.Budget != nil {
+		rule.Budget, err = r.Budget.parse()
+		if err != nil {
+			return rule, fmt.Errorf("budget: %v", err)
+		}
+	}
+	if r.DrainFactor != nil {
+		rule.DrainFactor = *r.DrainFactor
+	}
+	if rule.DrainFactor < 0 {
+		return rule, errors.New("drain_factor can't be negative")
+	}
+	rule.Repeat, err = parseDuration("repeat", r.Repeat)
+	if err != nil {
+		return rule, err
+	}
+	if rule.Repeat < 0 {
+		return rule, errors.New("repeat can't be negative")
+	}
+	rule.Schedule, err = schedule.Parse(r.Schedule)
+	if err != nil {
+		return rule, err
+	}
+	if rule.Category != "" && !category.Valid(rule.Category) {
+		return rule, fmt.Errorf("unknown category %q, use productive, neutral or distracting", rule.Category)
+	}
+	for i, s := range r.Escalation {
+		step, err := s.parse(e)
+		if err != nil {
+			return rule, fmt.Errorf("escalation step %d: %v", i+1, err)
+		}
+		if i > 0 && step.After < rule.Escalation[i-1].After {
+			return rule, fmt.Errorf("escalation step %d: after has to be at least the previous step's", i+1)
+		}
+		rule.Escalation = append(rule.Escalation, step)
+	}
+	return rule, nil
+}
+
+func (b rawBudget) parse() (annoy.Budget, error) {
+	budget := annoy.Budget{Warnings: b.Warnings}
+	var err error
+	budget.Daily, err = parseDuration("daily", b.Daily)
+	if err != nil {
+		return budget, err
+	}
+	if budget.Daily <= 0 {
+		return budget, errors.New("daily must be positive")
+	}
+	if b.Reset != "" {
+		reset, err := time.Parse("15:04", b.Reset)
+		if err != nil {
+			return budget, fmt.Errorf("reset is not a valid time of day, use HH:MM")
+		}
+		budget.Reset = time.Duration(reset.Hour())*time.Hour + time.Duration(reset.Minute())*time.Minute
+	}
+	for i, warning := range budget.Warnings {
+		if warning <= 0 || warning >= 100 {
+			return budget, errors.New("warnings are percentages between 0 and 100")
+		}
+		if i > 0 && warning <= budget.Warnings[i-1] {
+			return budget, errors.New("warnings have to be in increasing order")
+		}
+	}
+	return budget, nil
+}
+
+func (s rawStep) parse(e enforce.Config) (annoy.Step, error) {
+	step := annoy.Step{
+		Urgency: notify.Normal,
+		Message: s.Message,
+		Action:  s.Action,
+		Target:  s.Target,
+		DryRun:  s.DryRun,
+	}
+	var err error
+	step.After, err = parseDuration("after", s.After)
+	if err != nil {
+		return step, err
+	}
+	if step.After < 0 {
+		return step, errors.New("after can't be negative")
+	}
+	if s.Urgency != "" {
+		step.Urgency, err = notify.ParseUrgency(s.Urgency)
+		if err != nil {
+			return step, err
+		}
+	}
+	if step.Action == annoy.ActionNone {
+		if step.Target != "" || step.DryRun {
+			return step, errors.New("target and dry_run only make sense with an action")
+		}
+		return step, nil
+	}
+	return step, enforce.Validate(e, step.Action, step.Target)
+}
@@ -0,0 +1,611 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dwetterau/glider/local/annoy"
+	"github.com/dwetterau/glider/local/category"
+	"github.com/dwetterau/glider/local/enforce"
+	"github.com/dwetterau/glider/local/focus"
+	"github.com/dwetterau/glider/local/notify"
+	"github.com/dwetterau/glider/local/thrash"
+	"github.com/dwetterau/glider/local/xscan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, contents string) (string, func()) {
+	dir, err := ioutil.TempDir("", "config_test_dir")
+	require.NoError(t, err)
+	path := filepath.Join(dir, "config.toml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0600))
+	return path, func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path, cleanup := writeConfig(t, `
+[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "3m"
+drain_factor = 0.5
+message = "Stop reading Slack."
+schedule = ["Mon-Fri 09:00-12:00", "*/30 14 * * *"]
+
+[[rule]]
+name = "youtube"
+title = 'YouTube'
+bucket_size = "10m"
+`)
+	defer cleanup()
+
+	c, err := Load(path)
+	require.NoError(t, err)
+	require.Len(t, c.Rules, 2)
+
+	slack := c.Rules[0]
+	assert.Equal(t, "slack", slack.Name)
+	assert.Equal(t, 3*time.Minute, slack.BucketSize)
+	assert.Equal(t, .5, slack.DrainFactor)
+	assert.Equal(t, "Stop reading Slack.", slack.Message)
+	assert.True(t, slack.Matches(xscan.Window{ApplicationName: "slack"}))
+	assert.False(t, slack.Matches(xscan.Window{ApplicationName: "Firefox"}))
+	assert.Equal(t, []string{"Mon-Fri 09:00-12:00", "*/30 14 * * *"}, slack.Schedule.Strings())
+
+	assert.Equal(t, map[string]notify.Config{"desktop": {Type: notify.TypeDBus}}, c.Notifiers)
+
+	youtube := c.Rules[1]
+	assert.Equal(t, 10*time.Minute, youtube.BucketSize)
+	assert.Equal(t, 1.0, youtube.DrainFactor)
+	assert.True(t, youtube.Matches(xscan.Window{Title: "Cats - YouTube - Google Chrome"}))
+}
+
+func TestLoadNotifiers(t *testing.T) {
+	path, cleanup := writeConfig(t, `
+[notifier.phone]
+type = "webhook"
+url = "https://example.com/hook"
+timeout = "5s"
+
+[notifier.bell]
+type = "terminal"
+
+[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "3m"
+notifiers = ["phone", "bell"]
+`)
+	defer cleanup()
+
+	c, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"phone", "bell"}, c.Rules[0].Notifiers)
+	// Nothing uses the desktop notifier, so it isn't added.
+	assert.Equal(t, map[string]notify.Config{
+		"phone": {Type: notify.TypeWebhook, URL: "https://example.com/hook", Timeout: 5 * time.Second},
+		"bell":  {Type: notify.TypeTerminal},
+	}, c.Notifiers)
+}
+
+func TestLoadEscalation(t *testing.T) {
+	path, cleanup := writeConfig(t, `
+[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "3m"
+repeat = "2m"
+
+  [[rule.escalation]]
+  after = "0s"
+
+  [[rule.escalation]]
+  after = "5m"
+  urgency = "critical"
+  message = "Seriously, stop."
+
+  [[rule.escalation]]
+  after = "10m"
+  urgency = "critical"
+  action = "minimize"
+
+  [[rule.escalation]]
+  after = "15m"
+  action = "focus"
+  dry_run = true
+
+  [[rule.escalation]]
+  after = "20m"
+  action = "close"
+
+[enforce]
+allowlist = ["firefox"]
+work_class = "Code"
+`)
+	defer cleanup()
+
+	c, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Minute, c.Rules[0].Repeat)
+	assert.Equal(t, []annoy.Step{
+		{After: 0, Urgency: notify.Normal},
+		{After: 5 * time.Minute, Urgency: notify.Critical, Message: "Seriously, stop."},
+		{After: 10 * time.Minute, Urgency: notify.Critical, Action: annoy.ActionMinimize},
+		{After: 15 * time.Minute, Urgency: notify.Normal, Action: annoy.ActionFocus, DryRun: true},
+		{After: 20 * time.Minute, Urgency: notify.Normal, Action: annoy.ActionClose},
+	}, c.Rules[0].Escalation)
+	assert.Equal(t, enforce.Config{Allowlist: []string{"firefox"}, WorkClass: "Code"}, c.Enforce)
+}
+
+func TestLoadBudget(t *testing.T) {
+	path, cleanup := writeConfig(t, `
+[[rule]]
+name = "email"
+application = "Thunderbird"
+
+  [rule.budget]
+  daily = "45m"
+  reset = "04:30"
+  warnings = [50, 90]
+
+[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "3m"
+budget = { daily = "1h" }
+`)
+	defer cleanup()
+
+	c, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), c.Rules[0].BucketSize)
+	assert.Equal(t, annoy.Budget{
+		Daily:    45 * time.Minute,
+		Reset:    4*time.Hour + 30*time.Minute,
+		Warnings: []float64{50, 90},
+	}, c.Rules[0].Budget)
+	assert.Equal(t, annoy.Budget{Daily: time.Hour}, c.Rules[1].Budget)
+}
+
+func TestLoadThrashing(t *testing.T) {
+	path, cleanup := writeConfig(t, `
+[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "3m"
+notifiers = ["bell"]
+
+[notifier.bell]
+type = "terminal"
+
+[thrashing]
+switches_per_minute = 6
+window = "10m"
+`)
+	defer cleanup()
+
+	c, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, thrash.Config{Threshold: 6, Window: 10 * time.Minute, Cooldown: 15 * time.Minute}, c.Thrashing)
+	// The alerts go to the default notifier.
+	assert.Contains(t, c.Notifiers, "desktop")
+
+	// Without a [thrashing] section there are no alerts.
+	path, cleanup = writeConfig(t, `
+[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "3m"
+`)
+	defer cleanup()
+	c, err = Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, c.Thrashing.Threshold)
+	assert.Equal(t, 5*time.Minute, c.Thrashing.Window)
+}
+
+func TestLoadFocus(t *testing.T) {
+	path, cleanup := writeConfig(t, `
+[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "3m"
+
+[focus]
+allowlist = ["firefox", "zathura"]
+break = "0s"
+`)
+	defer cleanup()
+
+	c, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, focus.Config{Allowlist: []string{"firefox", "zathura"}, Repeat: time.Minute}, c.Focus)
+}
+
+func TestLoadBrowser(t *testing.T) {
+	path, cleanup := writeConfig(t, `
+[[rule]]
+name = "youtube"
+domain = "www.YouTube.com"
+bucket_size = "10m"
+
+[browser]
+listen = "127.0.0.1:8377"
+sites = { "Lobsters" = "lobste.rs" }
+`)
+	defer cleanup()
+
+	c, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "youtube.com", c.Rules[0].Domain)
+	assert.True(t, c.Rules[0].Matches(xscan.Window{ApplicationName: "firefox", Domain: "m.youtube.com"}))
+	assert.False(t, c.Rules[0].Matches(xscan.Window{ApplicationName: "firefox"}))
+	assert.Equal(t, Browser{Sites: map[string]string{"Lobsters": "lobste.rs"}, Listen: "127.0.0.1:8377"}, c.Browser)
+}
+
+func TestLoadCategories(t *testing.T) {
+	path, cleanup := writeConfig(t, `
+[[rule]]
+name = "docs"
+domain = "docs.python.org"
+bucket_size = "1h"
+category = "productive"
+
+[categories]
+productive = ["zathura"]
+distracting = ["discord"]
+end_of_day = "17:30"
+`)
+	defer cleanup()
+
+	c, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, category.Productive, c.Rules[0].Category)
+	assert.Equal(t, category.Config{
+		Applications: map[string][]string{
+			category.Productive:  {"zathura"},
+			category.Distracting: {"discord"},
+			category.Neutral:     nil,
+		},
+		EndOfDay: 17*time.Hour + 30*time.Minute,
+	}, c.Categories)
+}
+
+func TestLoadCalendar(t *testing.T) {
+	path, cleanup := writeConfig(t, `
+[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "3m"
+
+[calendar]
+paths = ["~/calendars/work.ics", "/var/lib/caldav-export"]
+during_meetings = "meetings"
+
+[[profile.meetings.rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "15m"
+`)
+	defer cleanup()
+
+	c, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, Calendar{
+		Paths:   []string{filepath.Join(os.Getenv("HOME"), "calendars/work.ics"), "/var/lib/caldav-export"},
+		Profile: "meetings",
+	}, c.Calendar)
+	require.Len(t, c.Profiles["meetings"], 1)
+	assert.Equal(t, 15*time.Minute, c.Profiles["meetings"][0].BucketSize)
+}
+
+func TestLoadTasks(t *testing.T) {
+	path, cleanup := writeConfig(t, `
+[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "3m"
+
+[tasks]
+todo_txt = "~/todo/todo.txt"
+taskwarrior = "/tmp/tasks.json"
+
+[tasks.contexts]
+work = ["Mon-Fri 09:00-17:00"]
+`)
+	defer cleanup()
+
+	c, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(os.Getenv("HOME"), "todo/todo.txt"), c.Tasks.TodoTxt)
+	assert.Equal(t, "/tmp/tasks.json", c.Tasks.Taskwarrior)
+	require.Len(t, c.Tasks.Contexts, 1)
+	assert.Equal(t, []string{"Mon-Fri 09:00-17:00"}, c.Tasks.Contexts["work"].Strings())
+}
+
+func TestLoadProjects(t *testing.T) {
+	path, cleanup := writeConfig(t, `
+[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "3m"
+
+[projects]
+roots = ["~/src", "/srv/work"]
+
+[[projects.extractor]]
+application = "^emacs$"
+title = '\[(?P<project>[^\]]+)\]$'
+
+[[projects.extractor]]
+title = '^(\S+) :: '
+`)
+	defer cleanup()
+
+	c, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(os.Getenv("HOME"), "src"), "/srv/work"}, c.Projects.Roots)
+	require.Len(t, c.Projects.Extractors, 2)
+	assert.Equal(t, "^emacs$", c.Projects.Extractors[0].Application.String())
+	assert.Equal(t, `\[(?P<project>[^\]]+)\]$`, c.Projects.Extractors[0].Title.String())
+	assert.True(t, c.Projects.Extractors[1].Application.MatchString("anything"))
+}
+
+func TestLoadErrors(t *testing.T) {
+	for _, testCase := range []struct {
+		contents string
+		err      string
+	}{
+		{
+			contents: ``,
+			err:      "no rules defined",
+		},
+		{
+			contents: `[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "3 minutes"`,
+			err: "bucket_size",
+		},
+		{
+			contents: `[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "3m"
+
+[[rule]]
+name = "gmail"
+title = "Gmail("
+bucket_size = "5m"`,
+			err: `rule 2 ("gmail"): title is not a valid regex`,
+		},
+		{
+			contents: `[[rule]]
+name = "slack"
+bucket_size = "3m"`,
+			err: `rule 1 ("slack"): at least one of application, title, executable or domain is required`,
+		},
+		{
+			contents: `[[rule]]
+name = "slack"
+application = "Slack"`,
+			err: `rule 1 ("slack"): bucket_size must be positive`,
+		},
+		{
+			contents: `[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "3m"
+drain_factor = -1`,
+			err: `rule 1 ("slack"): drain_factor can't be negative`,
+		},
+		{
+			contents: `[[rule]]
+application = "Slack"
+bucket_size = "3m"`,
+			err: `rule 1 (""): name is required`,
+		},
+		{
+			contents: `[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "3m"
+
+[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "3m"`,
+			err: `rule 2 ("slack"): another rule already has this name`,
+		},
+		{
+			contents: `[[rule]]
+name = "slack"
+aplication = "Slack"
+bucket_size = "3m"`,
+			err: "unknown keys rule.aplication",
+		},
+		{
+			contents: `[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "3m"
+notifiers = ["phone"]`,
+			err: `rule 1 ("slack"): there's no [notifier.phone]`,
+		},
+		{
+			contents: `[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "3m"
+  [[rule.escalation]]
+  after = "5m"
+  [[rule.escalation]]
+  after = "1m"`,
+			err: `rule 1 ("slack"): escalation step 2: after has to be at least the previous step's`,
+		},
+		{
+			contents: `[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "3m"
+  [[rule.escalation]]
+  action = "explode"`,
+			err: `rule 1 ("slack"): escalation step 1: unknown action "explode"`,
+		},
+		{
+			contents: `[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "3m"
+  [[rule.escalation]]
+  action = "focus"`,
+			err: `rule 1 ("slack"): escalation step 1: focus needs a target or an [enforce] work_class`,
+		},
+		{
+			contents: `[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "3m"
+schedule = ["Mon-Fri 9am-noon"]`,
+			err: `rule 1 ("slack"): schedule "Mon-Fri 9am-noon": invalid time "9am"`,
+		},
+		{
+			contents: `[[rule]]
+name = "email"
+application = "Thunderbird"
+budget = { daily = "45m", warnings = [80, 50] }`,
+			err: `rule 1 ("email"): budget: warnings have to be in increasing order`,
+		},
+		{
+			contents: `[[rule]]
+name = "email"
+application = "Thunderbird"
+budget = { daily = "45m", reset = "4am" }`,
+			err: `rule 1 ("email"): budget: reset is not a valid time of day`,
+		},
+		{
+			contents: `[focus]
+repeat = "0s"`,
+			err: `focus: repeat must be positive`,
+		},
+		{
+			contents: `[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "3m"
+category = "evil"`,
+			err: `rule 1 ("slack"): unknown category "evil"`,
+		},
+		{
+			contents: `[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "3m"
+
+[calendar]
+during_meetings = "quiet"`,
+			err: `calendar: during_meetings should be suspend or a profile`,
+		},
+		{
+			contents: `[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "3m"
+
+[[profile.quiet.rule]]
+name = "slack"`,
+			err: `profile "quiet": rule 1 ("slack"): at least one of application`,
+		},
+		{
+			contents: `[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "3m"
+
+[tasks.contexts]
+work = ["weekdays"]`,
+			err: `tasks: context "work": schedule "weekdays"`,
+		},
+		{
+			contents: `[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "3m"
+
+[[projects.extractor]]
+application = "emacs"
+title = "emacs"`,
+			err: `projects: extractor 1: title needs a group that captures the project`,
+		},
+		{
+			contents: `[categories]
+end_of_day = "6pm"`,
+			err: `categories: end_of_day is not a valid time of day`,
+		},
+		{
+			contents: `[thrashing]
+window = "10s"`,
+			err: `thrashing: window has to be at least a minute`,
+		},
+		{
+			contents: `[thrashing]
+notifiers = ["phone"]`,
+			err: `thrashing: there's no [notifier.phone]`,
+		},
+		{
+			contents: `[notifier.phone]
+type = "webhook"`,
+			err: `notifier "phone": url is required for webhooks`,
+		},
+		{
+			contents: `[notifier.phone]
+type = "pigeon"`,
+			err: `notifier "phone": unknown type "pigeon"`,
+		},
+	} {
+		path, cleanup := writeConfig(t, testCase.contents)
+		_, err := Load(path)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), path)
+			assert.Contains(t, err.Error(), testCase.err)
+		}
+		cleanup()
+	}
+}
+
+func TestLoadOrDefault(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config_test_dir")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	oldConfigHome := os.Getenv("XDG_CONFIG_HOME")
+	defer os.Setenv("XDG_CONFIG_HOME", oldConfigHome)
+	os.Setenv("XDG_CONFIG_HOME", dir)
+
+	c, err := LoadOrDefault(DefaultPath())
+	require.NoError(t, err)
+	assert.Equal(t, Default(), c)
+
+	// An explicitly requested config file has to exist.
+	_, err = LoadOrDefault(filepath.Join(dir, "missing.toml"))
+	assert.Error(t, err)
+	// No config behaves like a config that only has rules, e.g. there are no thrashing
+	// alerts unless they're asked for.
+	rulesOnly := filepath.Join(dir, "rules.toml")
+	require.NoError(t, ioutil.WriteFile(rulesOnly, []byte(`
+[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "3m"
+`), 0600))
+	c, err = Load(rulesOnly)
+	require.NoError(t, err)
+	assert.Equal(t, Default().Notifiers, c.Notifiers)
+	assert.Equal(t, Default().Thrashing, c.Thrashing)
+	assert.Equal(t, Default().Focus, c.Focus)
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// New settings for a rule in the config file, see UpdateRules.
+type RuleUpdate struct {
+	BucketSize  time.Duration
+	DrainFactor float64
+}
+
+// Matches the keys UpdateRules cares about at the top level of a [[rule]] table.
+var ruleKey = regexp.MustCompile(`^(\s*)(name|bucket_size|drain_factor)\s*=`)
+
+// Sets the bucket_size and drain_factor of the named rules in the config file at path.
+// The rest of the file, comments included, stays as it is. The file is only replaced if
+// the result still loads.
+func UpdateRules(path string, updates map[string]RuleUpdate) error {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(string(contents), "\n")
+
+	// The line numbers of the keys in each [[rule]] table, in order.
+	type ruleLines struct {
+		name, bucketSize, drainFactor int
+		indent                        string
+	}
+	var rules []ruleLines
+	inRule := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			header := strings.Replace(strings.SplitN(trimmed, "#", 2)[0], " ", "", -1)
+			inRule = header == "[[rule]]"
+			if inRule {
+				rules = append(rules, ruleLines{name: -1, bucketSize: -1, drainFactor: -1})
+			}
+			continue
+		}
+		match := ruleKey.FindStringSubmatch(line)
+		if !inRule || match == nil {
+			continue
+		}
+		r := &rules[len(rules)-1]
+		switch match[2] {
+		case "name":
+			r.name = i
+			r.indent = match[1]
+		case "bucket_size":
+			r.bucketSize = i
+		case "drain_factor":
+			r.drainFactor = i
+		}
+	}
+
+	// Lines added after a rule's name, keyed by the line number of the name.
+	added := make(map[int][]string)
+	found := make(map[string]bool, len(updates))
+	for _, r := range rules {
+		if r.name == -1 {
+			continue
+		}
+		var named struct {
+			Name string `toml:"name"`
+		}
+		if _, err := toml.Decode(lines[r.name], &named); err != nil {
+			return fmt.Errorf("%s:%d: %v", path, r.name+1, err)
+		}
+		update, ok := updates[named.Name]
+		if !ok {
+			continue
+		}
+		found[named.Name] = true
+		for _, key := range []struct {
+			line  int
+			name  string
+			value string
+		}{
+			{r.bucketSize, "bucket_size", strconv.Quote(formatDuration(update.BucketSize))},
+			{r.drainFactor, "drain_factor", strconv.FormatFloat(update.DrainFactor, 'f', -1, 64)},
+		} {
+			if key.line == -1 {
+				added[r.name] = append(added[r.name], fmt.Sprintf("%s%s = %s", r.indent, key.name, key.value))
+				continue
+			}
+			lines[key.line] = setValue(lines[key.line], key.name, key.value)
+		}
+	}
+	for name := range updates {
+		if !found[name] {
+			return fmt.Errorf("%s: there's no rule named %q", path, name)
+		}
+	}
+	updated := make([]string, 0, len(lines))
+	for i, line := range lines {
+		updated = append(updated, line)
+		updated = append(updated, added[i]...)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	temp, err := ioutil.TempFile(filepath.Dir(path), ".config.toml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	_, err = temp.WriteString(strings.Join(updated, "\n"))
+	if err == nil {
+		err = temp.Chmod(info.Mode())
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := Load(temp.Name()); err != nil {
+		return fmt.Errorf("the updated config doesn't load: %v", err)
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+// Replaces the value on a `key = value` line, keeping its indentation and any comment
+// after the value. Only works for values that can't contain a #.
+func setValue(line, key, value string) string {
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+	comment := ""
+	if i := strings.Index(line, "#"); i != -1 {
+		comment = " " + line[i:]
+	}
+	return fmt.Sprintf("%s%s = %s%s", indent, key, value, comment)
+}
+
+// Formats durations the way they're usually written in the config, e.g. "5m" rather
+// than "5m0s".
+func formatDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
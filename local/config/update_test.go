@@ -0,0 +1,73 @@
+package config
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateRules(t *testing.T) {
+	path, cleanup := writeConfig(t, `# My rules
+[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "3m" # Just enough to read a thread
+drain_factor = 0.5
+
+  [[rule.escalation]]
+  after = "0s"
+
+[[rule]]
+  name = 'gmail'
+  title = 'G?[mM]ail -'
+  bucket_size = "5m"
+
+[[rule]]
+name = "youtube"
+title = 'YouTube'
+bucket_size = "10m"
+`)
+	defer cleanup()
+
+	err := UpdateRules(path, map[string]RuleUpdate{
+		"slack": {BucketSize: 4 * time.Minute, DrainFactor: .3},
+		"gmail": {BucketSize: 90 * time.Minute, DrainFactor: 2},
+	})
+	require.NoError(t, err)
+	contents, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `# My rules
+[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "4m" # Just enough to read a thread
+drain_factor = 0.3
+
+  [[rule.escalation]]
+  after = "0s"
+
+[[rule]]
+  name = 'gmail'
+  drain_factor = 2
+  title = 'G?[mM]ail -'
+  bucket_size = "1h30m"
+
+[[rule]]
+name = "youtube"
+title = 'YouTube'
+bucket_size = "10m"
+`, string(contents))
+
+	err = UpdateRules(path, map[string]RuleUpdate{"reddit": {BucketSize: time.Minute, DrainFactor: 1}})
+	assert.EqualError(t, err, path+`: there's no rule named "reddit"`)
+
+	// Nothing is written if the result doesn't load.
+	err = UpdateRules(path, map[string]RuleUpdate{"slack": {BucketSize: -time.Minute, DrainFactor: 1}})
+	assert.Error(t, err)
+	unchanged, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, contents, unchanged)
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Editors tend to write a file in several steps, so wait for things to settle down
+// before reloading.
+const reloadDelay = 200 * time.Millisecond
+
+// Watches the config file at path and sends the new config every time it changes. A
+// config that fails validation is logged and skipped, so the receiver keeps using the
+// last good one. The returned channel is closed once done is closed.
+func Watch(path string, done <-chan struct{}) (<-chan *Config, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// Watch the directory rather than the file itself, since editors often replace the
+	// file with a new one instead of writing to it.
+	err = watcher.Add(filepath.Dir(path))
+	if err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	configs := make(chan *Config)
+	go func() {
+		defer close(configs)
+		defer watcher.Close()
+		var reload <-chan time.Time
+		for {
+			select {
+			case event := <-watcher.Events:
+				if filepath.Clean(event.Name) == filepath.Clean(path) {
+					reload = time.After(reloadDelay)
+				}
+			case err := <-watcher.Errors:
+				fmt.Printf("Error watching the config file: %v\n", err)
+			case <-reload:
+				reload = nil
+				c, err := Load(path)
+				if err != nil {
+					fmt.Printf("Keeping the old rules, the new config is invalid: %v\n", err)
+					continue
+				}
+				select {
+				case configs <- c:
+				case <-done:
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return configs, nil
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const slackRule = `[[rule]]
+name = "slack"
+application = "Slack"
+bucket_size = "%s"
+`
+
+func TestWatch(t *testing.T) {
+	path, cleanup := writeConfig(t, fmt.Sprintf(slackRule, "3m"))
+	defer cleanup()
+
+	done := make(chan struct{})
+	defer close(done)
+	configs, err := Watch(path, done)
+	require.NoError(t, err)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(fmt.Sprintf(slackRule, "5m")), 0600))
+	select {
+	case c := <-configs:
+		assert.Equal(t, 5*time.Minute, c.Rules[0].BucketSize)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the config was never reloaded")
+	}
+
+	// Invalid configs are skipped.
+	require.NoError(t, ioutil.WriteFile(path, []byte(fmt.Sprintf(slackRule, "-5m")), 0600))
+	select {
+	case c := <-configs:
+		t.Fatal("reloaded an invalid config", c)
+	case <-time.After(2 * reloadDelay):
+	}
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(fmt.Sprintf(slackRule, "7m")), 0600))
+	select {
+	case c := <-configs:
+		assert.Equal(t, 7*time.Minute, c.Rules[0].BucketSize)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the config was never reloaded")
+	}
+}
@@ -0,0 +1,206 @@
+package control
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Commands understood by the daemon.
+const (
+	CommandPause  = "pause"
+	CommandResume = "resume"
+	// Stops a rule from annoying for Duration. Its bucket keeps filling up in the meantime.
+	CommandSnooze = "snooze"
+	// Empties a rule's bucket.
+	CommandReset  = "reset"
+	CommandStatus = "status"
+	// Starts a focus session that lasts Duration, followed by a break that lasts Break,
+	// or the configured break if Break is empty.
+	CommandFocus = "focus"
+	// Ends the current focus session early.
+	CommandStopFocus = "stop-focus"
+)
+
+// Every connection carries a single request followed by a single response, each one a
+// JSON object.
+type Request struct {
+	Command string `json:"command"`
+	Rule    string `json:"rule,omitempty"`
+	// A duration like "30m", see time.ParseDuration.
+	Duration string `json:"duration,omitempty"`
+	Break    string `json:"break,omitempty"`
+}
+
+type Response struct {
+	Error  string  `json:"error,omitempty"`
+	Status *Status `json:"status,omitempty"`
+}
+
+type Status struct {
+	Paused    bool         `json:"paused"`
+	Rules     []RuleStatus `json:"rules"`
+	Switching Switching    `json:"switching"`
+	// Nil when there's no focus session.
+	Focus *FocusStatus `json:"focus,omitempty"`
+	// Nil if the score couldn't be worked out.
+	Score *Score `json:"score,omitempty"`
+	// Nil unless there's a meeting going on.
+	Meeting *Meeting `json:"meeting,omitempty"`
+}
+
+type Meeting struct {
+	Summary string    `json:"summary"`
+	Until   time.Time `json:"until"`
+	// The profile whose rules are in use, empty if the rules are suspended.
+	Profile string `json:"profile,omitempty"`
+}
+
+// How today went so far, see the category package.
+type Score struct {
+	Value       int           `json:"value"`
+	Productive  time.Duration `json:"productive"`
+	Neutral     time.Duration `json:"neutral"`
+	Distracting time.Duration `json:"distracting"`
+	// The applications with the most distracting time, most first.
+	Distractions []Distraction `json:"distractions,omitempty"`
+}
+
+type Distraction struct {
+	Application string        `json:"application"`
+	Duration    time.Duration `json:"duration"`
+}
+
+type FocusStatus struct {
+	// Either focus or break.
+	Phase string `json:"phase"`
+	// When the current phase ends.
+	Until      time.Time     `json:"until"`
+	Focused    time.Duration `json:"focused"`
+	Distracted time.Duration `json:"distracted"`
+}
+
+// How often focus moved between applications recently.
+type Switching struct {
+	Switches int `json:"switches"`
+	// The switches are counted over this long.
+	Window    time.Duration `json:"window"`
+	PerMinute float64       `json:"per_minute"`
+	// Zero if thrashing alerts are off.
+	Threshold float64      `json:"threshold,omitempty"`
+	TopPairs  []SwitchPair `json:"top_pairs,omitempty"`
+}
+
+// How many times focus moved between two applications, in either direction.
+type SwitchPair struct {
+	A     string `json:"a"`
+	B     string `json:"b"`
+	Count int    `json:"count"`
+}
+
+type RuleStatus struct {
+	Name         string        `json:"name"`
+	Bucket       time.Duration `json:"bucket"`
+	BucketSize   time.Duration `json:"bucket_size"`
+	SnoozedUntil *time.Time    `json:"snoozed_until,omitempty"`
+	// Zero if the rule has no daily budget.
+	Budget     time.Duration `json:"budget,omitempty"`
+	BudgetUsed time.Duration `json:"budget_used,omitempty"`
+	// Set while the rule is outside of its schedule.
+	OffSchedule bool `json:"off_schedule,omitempty"`
+	// The most recent escalation steps that fired, oldest first.
+	Escalations []Escalation `json:"escalations,omitempty"`
+}
+
+type Escalation struct {
+	Time time.Time `json:"time"`
+	// The index of the step in the rule's escalation policy.
+	Step        int    `json:"step"`
+	Urgency     string `json:"urgency"`
+	Action      string `json:"action,omitempty"`
+	WindowTitle string `json:"window_title"`
+}
+
+// Returns $XDG_RUNTIME_DIR/glider/control.sock, falling back to a per-user directory in
+// the temporary directory.
+func DefaultSocketPath() string {
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = filepath.Join(os.TempDir(), fmt.Sprintf("glider-%d", os.Getuid()))
+	}
+	return filepath.Join(runtimeDir, "glider", "control.sock")
+}
+
+// Listens on the unix socket at path and answers every request with handle. Requests
+// are handled one at a time. Closing the returned listener stops serving.
+func Serve(path string, handle func(Request) Response) (net.Listener, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return nil, err
+	}
+	// A socket left behind by a daemon that didn't shut down cleanly would make Listen
+	// fail, but one that's still accepting connections belongs to a running daemon.
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("%s is in use, is the daemon already running?", path)
+	}
+	os.Remove(path)
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			serve(conn, handle)
+		}
+	}()
+	return listener, nil
+}
+
+func serve(conn net.Conn, handle func(Request) Response) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	var request Request
+	response := Response{}
+	err := json.NewDecoder(conn).Decode(&request)
+	if err != nil {
+		response.Error = fmt.Sprintf("invalid request: %v", err)
+	} else {
+		response = handle(request)
+	}
+	err = json.NewEncoder(conn).Encode(&response)
+	if err != nil {
+		fmt.Printf("Unable to reply to a control request: %v\n", err)
+	}
+}
+
+// Sends a request to the daemon listening at path. Errors reported by the daemon are
+// returned as errors.
+func Send(path string, request Request) (Response, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return Response{}, fmt.Errorf("unable to reach the daemon, is it running? %v", err)
+	}
+	defer conn.Close()
+	err = json.NewEncoder(conn).Encode(&request)
+	if err != nil {
+		return Response{}, err
+	}
+	var response Response
+	err = json.NewDecoder(conn).Decode(&response)
+	if err != nil {
+		return Response{}, err
+	}
+	if response.Error != "" {
+		return response, errors.New(response.Error)
+	}
+	return response, nil
+}
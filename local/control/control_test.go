@@ -0,0 +1,74 @@
+package control
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeAndSend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "control_test_dir")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "glider", "control.sock")
+
+	var requests []Request
+	listener, err := Serve(path, func(request Request) Response {
+		requests = append(requests, request)
+		switch request.Command {
+		case CommandStatus:
+			return Response{Status: &Status{
+				Paused: true,
+				Rules:  []RuleStatus{{Name: "slack", Bucket: time.Minute, BucketSize: 3 * time.Minute}},
+			}}
+		case CommandSnooze:
+			return Response{}
+		default:
+			return Response{Error: "unknown command " + request.Command}
+		}
+	})
+	require.NoError(t, err)
+	defer listener.Close()
+
+	// Only one daemon can listen at a time.
+	_, err = Serve(path, nil)
+	assert.Error(t, err)
+
+	response, err := Send(path, Request{Command: CommandStatus})
+	require.NoError(t, err)
+	assert.Equal(t, &Status{
+		Paused: true,
+		Rules:  []RuleStatus{{Name: "slack", Bucket: time.Minute, BucketSize: 3 * time.Minute}},
+	}, response.Status)
+
+	_, err = Send(path, Request{Command: CommandSnooze, Rule: "slack", Duration: "30m"})
+	require.NoError(t, err)
+
+	_, err = Send(path, Request{Command: "explode"})
+	assert.EqualError(t, err, "unknown command explode")
+
+	assert.Equal(t, []Request{
+		{Command: CommandStatus},
+		{Command: CommandSnooze, Rule: "slack", Duration: "30m"},
+		{Command: "explode"},
+	}, requests)
+}
+
+func TestServeReplacesStaleSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "control_test_dir")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "control.sock")
+	require.NoError(t, ioutil.WriteFile(path, nil, 0600))
+
+	listener, err := Serve(path, func(Request) Response { return Response{} })
+	require.NoError(t, err)
+	defer listener.Close()
+	_, err = Send(path, Request{Command: CommandPause})
+	assert.NoError(t, err)
+}
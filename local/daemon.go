@@ -0,0 +1,745 @@
+package local
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"time"
+
+	"github.com/dwetterau/glider/local/annoy"
+	"github.com/dwetterau/glider/local/browser"
+	"github.com/dwetterau/glider/local/calendar"
+	"github.com/dwetterau/glider/local/category"
+	"github.com/dwetterau/glider/local/clock"
+	"github.com/dwetterau/glider/local/config"
+	"github.com/dwetterau/glider/local/control"
+	"github.com/dwetterau/glider/local/enforce"
+	"github.com/dwetterau/glider/local/focus"
+	"github.com/dwetterau/glider/local/idle"
+	"github.com/dwetterau/glider/local/notify"
+	"github.com/dwetterau/glider/local/project"
+	"github.com/dwetterau/glider/local/state"
+	"github.com/dwetterau/glider/local/task"
+	"github.com/dwetterau/glider/local/thrash"
+	"github.com/dwetterau/glider/local/track"
+	"github.com/dwetterau/glider/local/xscan"
+)
+
+// Everything the daemon and the commands that inspect it need to know where to look.
+type Options struct {
+	ConfigPath    string
+	SampleRate    time.Duration
+	Scanner       string
+	IdleSource    string
+	IdleThreshold time.Duration
+	StatePath     string
+	TrackPath     string
+	SocketPath    string
+	// Where to record every window the scanner finds, empty to not record. See
+	// xscan.NewRecorder.
+	RecordPath string
+}
+
+func DefaultOptions() Options {
+	return Options{
+		ConfigPath:    config.DefaultPath(),
+		SampleRate:    5 * time.Second,
+		Scanner:       xscan.BackendAuto,
+		IdleSource:    idle.SourceAuto,
+		IdleThreshold: 5 * time.Minute,
+		StatePath:     state.DefaultPath(),
+		TrackPath:     track.DefaultPath(),
+		SocketPath:    control.DefaultSocketPath(),
+	}
+}
+
+type daemon struct {
+	options  Options
+	idle     *idle.Detector
+	tracker  track.Tracker
+	enforcer *enforce.Enforcer
+	annoyer  annoy.Annoyer
+	// The rules from the config, and the profiles that can take their place.
+	rules      []annoy.Rule
+	profiles   map[string][]annoy.Rule
+	notifiers  map[string]notify.Notifier
+	detector   *thrash.Detector
+	focus      focus.Config
+	classifier *browser.Classifier
+	projects   *project.Classifier
+	categories category.Config
+	// Built from the categories and the rules' categories.
+	categorizer *category.Categorizer
+	// What the browser extension bridge listens on, if anything.
+	bridgeAddress string
+
+	window xscan.Window
+	// The last application that had focus since we were last idle.
+	lastApplication string
+	lastCharged     time.Time
+	idleFor         time.Duration
+	totalIdle       time.Duration
+	saved           state.State
+	paused          bool
+	// Nil unless there's a focus session going on.
+	session *focus.Session
+	// When to send the end of day summary next, zero for never.
+	nextSummary time.Time
+
+	calendar         config.Calendar
+	events           []calendar.Event
+	calendarChecked  time.Time
+	calendarModified time.Time
+	// The meetings from a day before until meetingsUntil.
+	meetings      []calendar.Occurrence
+	meetingsUntil time.Time
+	// Nil unless there's a meeting going on.
+	meeting *calendar.Occurrence
+	// Where the tasks that nags suggest come from.
+	tasks task.Config
+}
+
+// A control request waiting for the main loop to handle it.
+type controlCall struct {
+	request control.Request
+	reply   chan control.Response
+}
+
+// Watches the focused window and annoys as needed until the process is interrupted.
+func Run(options Options) error {
+	fmt.Println("Taking off!")
+	c, err := config.LoadOrDefault(options.ConfigPath)
+	if err != nil {
+		return fmt.Errorf("invalid config: %v", err)
+	}
+	scanner, err := xscan.NewBackend(options.Scanner)
+	if err != nil {
+		return fmt.Errorf("unable to start the %s scanner: %v", options.Scanner, err)
+	}
+	if options.RecordPath != "" {
+		recording, err := os.OpenFile(options.RecordPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return fmt.Errorf("unable to record windows: %v", err)
+		}
+		defer recording.Close()
+		scanner = xscan.NewRecorder(scanner, recording, clock.Real{})
+	}
+	idleSource, err := idle.New(options.IdleSource)
+	if err != nil {
+		return fmt.Errorf("unable to start the %s idle source: %v", options.IdleSource, err)
+	}
+	tracker, err := track.NewSQLite(options.TrackPath)
+	if err != nil {
+		return fmt.Errorf("unable to open the tracking log: %v", err)
+	}
+	notifiers, err := notify.NewAll(c.Notifiers)
+	if err != nil {
+		return err
+	}
+	var bridge *browser.Bridge
+	if c.Browser.Listen != "" {
+		bridge, err = browser.ServeBridge(c.Browser.Listen, c.Browser.Token)
+		if err != nil {
+			return fmt.Errorf("unable to listen for the browser extension: %v", err)
+		}
+		defer bridge.Close()
+	}
+	enforcer := enforce.New(c.Enforce)
+	d := &daemon{
+		options:       options,
+		idle:          idle.NewDetector(idleSource, options.IdleThreshold),
+		tracker:       tracker,
+		enforcer:      enforcer,
+		annoyer:       annoy.NewAnnoyer(c.Rules, notifiers, enforcer),
+		rules:         c.Rules,
+		profiles:      c.Profiles,
+		notifiers:     notifiers,
+		detector:      thrash.NewDetector(c.Thrashing),
+		focus:         c.Focus,
+		classifier:    browser.NewClassifier(c.Browser.Sites, bridge),
+		projects:      project.NewClassifier(c.Projects),
+		bridgeAddress: c.Browser.Listen,
+		calendar:      c.Calendar,
+		tasks:         c.Tasks,
+		lastCharged:   time.Now(),
+	}
+	d.annoyer.SetNextTask(d.nextTask)
+	d.setCategories(c.Categories, c.Rules, time.Now())
+	d.saved, err = state.Load(options.StatePath)
+	if err != nil {
+		fmt.Printf("Unable to load the saved state, starting from scratch: %v\n", err)
+	} else if !d.saved.SavedAt.IsZero() {
+		// The buckets drain while we're not running, as if nothing was focused.
+		d.annoyer.Restore(d.saved.Buckets, time.Since(d.saved.SavedAt))
+		d.annoyer.RestoreBudgets(d.saved.Budgets)
+	}
+	d.checkCalendar(time.Now(), true)
+	d.updateMeeting(time.Now())
+
+	events, err := xscan.Watch(scanner, options.SampleRate, nil)
+	if err != nil {
+		return fmt.Errorf("unable to watch for focus changes: %v", err)
+	}
+	var configs <-chan *config.Config
+	if _, err := os.Stat(filepath.Dir(options.ConfigPath)); err == nil {
+		configs, err = config.Watch(options.ConfigPath, nil)
+		if err != nil {
+			fmt.Printf("Unable to watch the config for changes: %v\n", err)
+		}
+	}
+
+	calls := make(chan controlCall)
+	listener, err := control.Serve(options.SocketPath, func(request control.Request) control.Response {
+		call := controlCall{request: request, reply: make(chan control.Response, 1)}
+		calls <- call
+		return <-call.reply
+	})
+	if err != nil {
+		return fmt.Errorf("unable to listen for control requests: %v", err)
+	}
+	defer listener.Close()
+
+	// Focus changes arrive as events, but we still check in every sample so that staying
+	// on the same window long enough can trigger the annoyer.
+	ticker := time.NewTicker(options.SampleRate)
+	defer ticker.Stop()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				// Subscribing scanners stop when they lose their connection, e.g. to the
+				// X server. Polling wouldn't get any further.
+				d.land()
+				return errors.New("the window scanner stopped, is the display still running?")
+			}
+			// Everything up until the event was spent on the previous window.
+			d.charge(event.Time)
+			d.window = event.Window
+			d.switched(event)
+		case now := <-ticker.C:
+			d.charge(now)
+		case c := <-configs:
+			notifiers, err := notify.NewAll(c.Notifiers)
+			if err != nil {
+				fmt.Printf("Keeping the old rules: %v\n", err)
+				continue
+			}
+			fmt.Println("Reloaded the config from", options.ConfigPath)
+			d.charge(time.Now())
+			d.enforcer.SetConfig(c.Enforce)
+			d.notifiers = notifiers
+			d.rules = c.Rules
+			d.profiles = c.Profiles
+			d.calendar = c.Calendar
+			d.checkCalendar(time.Now(), true)
+			d.meeting = calendar.At(d.meetings, time.Now())
+			d.setRules()
+			d.forgetRemovedRules()
+			d.detector.SetConfig(c.Thrashing)
+			d.focus = c.Focus
+			d.classifier.SetSites(c.Browser.Sites)
+			d.projects.SetConfig(c.Projects)
+			d.setCategories(c.Categories, c.Rules, time.Now())
+			d.tasks = c.Tasks
+			if c.Browser.Listen != d.bridgeAddress {
+				fmt.Println("Restart glider to change the address it listens on for the browser extension")
+			}
+		case call := <-calls:
+			d.charge(time.Now())
+			call.reply <- d.handle(call.request)
+		case sig := <-signals:
+			d.land()
+			fmt.Println("Landing after", sig)
+			return nil
+		}
+	}
+}
+
+// Wraps up before exiting: charges the last stretch, ends any focus session and saves.
+func (d *daemon) land() {
+	d.charge(time.Now())
+	if d.session != nil {
+		d.session.Stop(time.Now())
+		d.endSession()
+	}
+	// Always save on the way out, so the downtime is measured from now.
+	d.saved.Buckets = nil
+	d.save(time.Now())
+}
+
+// Charges the time since the last charge to the focused window. Times that aren't after
+// the last charge, like an event stamped before a tick that was handled first, have
+// nothing left to charge.
+func (d *daemon) charge(now time.Time) {
+	if !now.After(d.lastCharged) {
+		return
+	}
+	duration := now.Sub(d.lastCharged)
+	d.lastCharged = now
+	defer d.save(now)
+	defer d.maybeSummarize(now)
+	defer d.advanceSession(now)
+	d.checkCalendar(now, false)
+	d.updateMeeting(now)
+
+	if d.idle.Idle() {
+		// Time away from the keyboard isn't spent on the focused window.
+		d.idleFor += duration
+		d.totalIdle += duration
+		d.lastApplication = ""
+		d.record(now, duration, xscan.IdleWindow, "")
+		if !d.suspended() {
+			d.annoyer.MaybeAnnoy(xscan.IdleWindow, duration)
+		}
+		return
+	}
+	if d.idleFor > 0 {
+		fmt.Printf("Welcome back! Idle for %v (%v in total)\n", d.idleFor, d.totalIdle)
+		d.idleFor = 0
+	}
+	// Tabs and directories can change without the window changing, so this can't be done
+	// once per event.
+	window := d.projects.Classify(d.classifier.Classify(d.window))
+	d.record(now, duration, window, d.annoyer.Match(window))
+	if d.session != nil && d.session.Charge(now, window, duration) && !d.paused {
+		nag := d.session.Nag(now)
+		if next := d.nextTask(now); next != "" {
+			nag += "\n\nNext up: " + next
+		}
+		d.notify(d.focus.Notifiers, nag)
+	}
+	if d.suspended() {
+		return
+	}
+	rule := d.annoyer.Match(window)
+	annoyed := d.annoyer.MaybeAnnoy(window, duration)
+	if annoyed {
+		fmt.Println("Annoyed for window: ", window)
+		// Logged so that glider tune can tell which nags were heeded.
+		if err := d.tracker.RecordNag(track.Nag{Time: now, Rule: rule}); err != nil {
+			fmt.Printf("Unable to record a nag: %v\n", err)
+		}
+	}
+}
+
+// Reports whether the buckets should be left exactly as they are, idle time included:
+// while paused until we're resumed, and during meetings unless a profile takes over.
+func (d *daemon) suspended() bool {
+	return d.paused || (d.meeting != nil && d.calendar.Profile == "")
+}
+
+// Counts focus moving to another application, and alerts if it's happening too often.
+func (d *daemon) switched(event xscan.FocusEvent) {
+	application := event.Window.ApplicationName
+	if application == "" {
+		return
+	}
+	last := d.lastApplication
+	d.lastApplication = application
+	if last == "" || last == application {
+		return
+	}
+	thrashing := d.detector.Observe(thrash.Switch{Time: event.Time, From: last, To: application})
+	if !thrashing || d.paused {
+		return
+	}
+	c := d.detector.Config()
+	switches := d.detector.Switches(event.Time)
+	d.notify(c.Notifiers, fmt.Sprintf(
+		"You switched windows %d times in the last %v, mostly %v. Pick one and stay there.",
+		len(switches), c.Window, thrash.TopPairs(switches, 1)[0],
+	))
+}
+
+// Moves the focus session along, and lets the user know when a phase is over.
+func (d *daemon) advanceSession(now time.Time) {
+	if d.session == nil {
+		return
+	}
+	previous := d.session.Phase
+	switch d.session.Advance(now) {
+	case focus.PhaseBreak:
+		d.notify(d.focus.Notifiers, d.session.Summary())
+	case focus.PhaseDone:
+		if previous == focus.PhaseFocus {
+			d.notify(d.focus.Notifiers, d.session.Summary())
+		} else {
+			d.notify(d.focus.Notifiers, "Your break is over.")
+		}
+		d.endSession()
+	}
+}
+
+// How often the calendar files are checked for changes.
+const calendarCheckInterval = time.Minute
+
+// Reloads the calendar files if they changed, and works out the meetings around now.
+// Forcing it skips waiting for the next check and reloads no matter what.
+func (d *daemon) checkCalendar(now time.Time, force bool) {
+	if len(d.calendar.Paths) == 0 {
+		d.events = nil
+		d.meetings = nil
+		return
+	}
+	if !force && now.Sub(d.calendarChecked) < calendarCheckInterval {
+		return
+	}
+	d.calendarChecked = now
+	if modified := calendar.LastModified(d.calendar.Paths); force || !modified.Equal(d.calendarModified) {
+		d.calendarModified = modified
+		events, err := calendar.Load(d.calendar.Paths)
+		if err != nil {
+			fmt.Printf("Some of the calendar couldn't be read: %v\n", err)
+		}
+		d.events = events
+		d.meetingsUntil = time.Time{}
+	}
+	// Expanding recurring events takes a while, so it's done a day at a time.
+	if now.Add(time.Hour).After(d.meetingsUntil) {
+		d.meetingsUntil = now.Add(24 * time.Hour)
+		d.meetings = calendar.Meetings(d.events, now.Add(-24*time.Hour), d.meetingsUntil)
+	}
+}
+
+// Notices meetings starting and ending, and switches the rules over to the meeting
+// profile and back.
+func (d *daemon) updateMeeting(now time.Time) {
+	meeting := calendar.At(d.meetings, now)
+	wasInMeeting := d.meeting != nil
+	d.meeting = meeting
+	if wasInMeeting == (meeting != nil) {
+		return
+	}
+	if meeting != nil {
+		fmt.Printf("In a meeting until %s: %s\n", meeting.End.Format(time.Kitchen), meeting.Event.Summary)
+	} else {
+		fmt.Println("Out of meetings")
+	}
+	d.setRules()
+}
+
+// Hands the annoyer the rules that apply right now.
+func (d *daemon) setRules() {
+	rules := d.rules
+	if d.meeting != nil && d.calendar.Profile != "" {
+		rules = d.profiles[d.calendar.Profile]
+	}
+	d.annoyer.SetRules(rules, d.notifiers)
+}
+
+// Drops what the annoyer kept for swapped out rules that are no longer in the config, in
+// the rules or any profile.
+func (d *daemon) forgetRemovedRules() {
+	keep := append([]annoy.Rule(nil), d.rules...)
+	for _, rules := range d.profiles {
+		keep = append(keep, rules...)
+	}
+	d.annoyer.Forget(keep)
+}
+
+func (d *daemon) setCategories(c category.Config, rules []annoy.Rule, now time.Time) {
+	d.categories = c
+	d.categorizer = newCategorizer(c, rules)
+	d.nextSummary = nextEndOfDay(now, c.EndOfDay)
+}
+
+// Puts time into the categories from the config, and the categories of the rules.
+func newCategorizer(c category.Config, rules []annoy.Rule) *category.Categorizer {
+	ruleCategories := make(map[string]string, len(rules))
+	for _, rule := range rules {
+		ruleCategories[rule.Name] = rule.Category
+	}
+	return category.NewCategorizer(c, ruleCategories)
+}
+
+// Returns the first time after now that's endOfDay past midnight, or the zero time if
+// endOfDay is negative.
+func nextEndOfDay(now time.Time, endOfDay time.Duration) time.Time {
+	if endOfDay < 0 {
+		return time.Time{}
+	}
+	// It's a time on the clock, which isn't endOfDay past midnight when the clocks change.
+	hour, minute := int(endOfDay/time.Hour), int(endOfDay%time.Hour/time.Minute)
+	year, month, day := now.Date()
+	next := time.Date(year, month, day, hour, minute, 0, 0, now.Location())
+	if !next.After(now) {
+		next = time.Date(year, month, day+1, hour, minute, 0, 0, now.Location())
+	}
+	return next
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// Sums up the day so far from the tracking log.
+func (d *daemon) summarize(now time.Time) (category.Summary, error) {
+	usage, err := d.tracker.Usage(startOfDay(now), now)
+	if err != nil {
+		return category.Summary{}, err
+	}
+	return d.categorizer.Summarize(usage), nil
+}
+
+// Sends the end of day summary once it's due.
+func (d *daemon) maybeSummarize(now time.Time) {
+	if d.nextSummary.IsZero() || now.Before(d.nextSummary) {
+		return
+	}
+	d.nextSummary = nextEndOfDay(now, d.categories.EndOfDay)
+	summary, err := d.summarize(now)
+	if err != nil {
+		fmt.Printf("Unable to sum up the day: %v\n", err)
+		return
+	}
+	d.notify(d.categories.Notifiers, summary.Message())
+}
+
+// Returns the task to work on at now, or "" if there's none. The task lists are read
+// every time, since they're edited outside of glider and only needed for nags.
+func (d *daemon) nextTask(now time.Time) string {
+	tasks, err := task.Load(d.tasks)
+	if err != nil {
+		fmt.Printf("Unable to read the tasks: %v\n", err)
+	}
+	next, ok := d.tasks.Next(tasks, now)
+	if !ok {
+		return ""
+	}
+	return next.String()
+}
+
+// Logs the focus session, which has to be done, and forgets about it.
+func (d *daemon) endSession() {
+	s := d.session
+	d.session = nil
+	err := d.tracker.RecordSession(track.Session{
+		Start:      s.Start,
+		End:        s.BreakEnd(),
+		Length:     s.Length,
+		Focused:    s.Focused,
+		Distracted: s.Distracted,
+		Break:      s.BreakLength,
+		Completed:  s.Completed(),
+	})
+	if err != nil {
+		fmt.Printf("Unable to record the focus session: %v\n", err)
+	}
+}
+
+// Sends a notification to the named notifiers. No names means the default notifier, or
+// every notifier if that isn't configured.
+func (d *daemon) notify(names []string, body string) {
+	if len(names) == 0 {
+		if _, ok := d.notifiers[annoy.DefaultNotifier]; ok {
+			names = []string{annoy.DefaultNotifier}
+		} else {
+			for name := range d.notifiers {
+				names = append(names, name)
+			}
+		}
+	}
+	notification := notify.Notification{Title: "Glider", Body: body, Urgency: notify.Normal}
+	for _, name := range names {
+		n, ok := d.notifiers[name]
+		if !ok {
+			fmt.Printf("There's no notifier named %s\n", name)
+			continue
+		}
+		if err := n.Notify(notification); err != nil {
+			fmt.Printf("Unable to notify with %s: %v\n", name, err)
+		}
+	}
+}
+
+func (d *daemon) handle(request control.Request) control.Response {
+	switch request.Command {
+	case control.CommandPause:
+		d.paused = true
+	case control.CommandResume:
+		d.paused = false
+	case control.CommandSnooze:
+		duration, err := time.ParseDuration(request.Duration)
+		if err != nil {
+			return control.Response{Error: fmt.Sprintf("invalid snooze duration: %v", err)}
+		}
+		if !d.annoyer.Snooze(request.Rule, time.Now().Add(duration)) {
+			return control.Response{Error: fmt.Sprintf("there's no rule named %q", request.Rule)}
+		}
+	case control.CommandReset:
+		if !d.annoyer.Reset(request.Rule) {
+			return control.Response{Error: fmt.Sprintf("there's no rule named %q", request.Rule)}
+		}
+		d.save(time.Now())
+	case control.CommandFocus:
+		return d.startSession(request)
+	case control.CommandStopFocus:
+		if d.session == nil {
+			return control.Response{Error: "there's no focus session to stop"}
+		}
+		wasFocusing := d.session.Phase == focus.PhaseFocus
+		d.session.Stop(time.Now())
+		if wasFocusing {
+			d.notify(d.focus.Notifiers, d.session.Summary())
+		}
+		d.endSession()
+	case control.CommandStatus:
+		return control.Response{Status: d.status()}
+	default:
+		return control.Response{Error: fmt.Sprintf("unknown command %q", request.Command)}
+	}
+	return control.Response{}
+}
+
+// How many of the most frequent switch pairs the status and the report show.
+const topSwitchPairs = 3
+
+// How many of the most distracting applications the status shows.
+const topDistractions = 3
+
+func (d *daemon) startSession(request control.Request) control.Response {
+	length, err := time.ParseDuration(request.Duration)
+	if err != nil || length <= 0 {
+		return control.Response{Error: fmt.Sprintf("invalid focus session length %q", request.Duration)}
+	}
+	breakLength := d.focus.Break
+	if request.Break != "" {
+		breakLength, err = time.ParseDuration(request.Break)
+		if err != nil || breakLength < 0 {
+			return control.Response{Error: fmt.Sprintf("invalid break length %q", request.Break)}
+		}
+	}
+	now := time.Now()
+	if d.session != nil {
+		if d.session.Phase == focus.PhaseFocus {
+			return control.Response{Error: fmt.Sprintf(
+				"there's already a focus session until %s, stop it first", d.session.End().Format(time.Kitchen),
+			)}
+		}
+		// Starting a new session cuts the break short.
+		d.session.Stop(now)
+		d.endSession()
+	}
+	d.session = focus.Start(d.focus, now, length, breakLength)
+	return control.Response{}
+}
+
+func (d *daemon) status() *control.Status {
+	now := time.Now()
+	switches := d.detector.Switches(now)
+	status := &control.Status{
+		Paused: d.paused,
+		Switching: control.Switching{
+			Switches:  len(switches),
+			Window:    d.detector.Config().Window,
+			PerMinute: d.detector.Rate(now),
+			Threshold: d.detector.Config().Threshold,
+		},
+	}
+	if d.session != nil {
+		status.Focus = &control.FocusStatus{
+			Phase:      d.session.Phase,
+			Until:      d.session.End(),
+			Focused:    d.session.Focused,
+			Distracted: d.session.Distracted,
+		}
+		if d.session.Phase == focus.PhaseBreak {
+			status.Focus.Until = d.session.BreakEnd()
+		}
+	}
+	if d.meeting != nil {
+		status.Meeting = &control.Meeting{
+			Summary: d.meeting.Event.Summary,
+			Until:   d.meeting.End,
+			Profile: d.calendar.Profile,
+		}
+	}
+	if summary, err := d.summarize(now); err != nil {
+		fmt.Printf("Unable to work out the focus score: %v\n", err)
+	} else {
+		status.Score = &control.Score{
+			Value:       summary.Score,
+			Productive:  summary.Totals[category.Productive],
+			Neutral:     summary.Totals[category.Neutral],
+			Distracting: summary.Totals[category.Distracting],
+		}
+		for i, distraction := range summary.Distractions {
+			if i == topDistractions {
+				break
+			}
+			status.Score.Distractions = append(status.Score.Distractions, control.Distraction{
+				Application: distraction.Name, Duration: distraction.Duration,
+			})
+		}
+	}
+	for _, pair := range thrash.TopPairs(switches, topSwitchPairs) {
+		status.Switching.TopPairs = append(status.Switching.TopPairs, control.SwitchPair{
+			A: pair.A, B: pair.B, Count: pair.Count,
+		})
+	}
+	buckets := d.annoyer.Buckets()
+	budgets := d.annoyer.Budgets()
+	for _, rule := range d.annoyer.Rules() {
+		ruleStatus := control.RuleStatus{
+			Name:        rule.Name,
+			Bucket:      buckets[rule.Name],
+			BucketSize:  rule.BucketSize,
+			OffSchedule: !rule.Active(now),
+			Budget:      rule.Budget.Daily,
+			BudgetUsed:  budgets[rule.Name].Used,
+		}
+		if until := d.annoyer.SnoozedUntil(rule.Name); !until.IsZero() {
+			ruleStatus.SnoozedUntil = &until
+		}
+		for _, escalation := range d.annoyer.History(rule.Name) {
+			step := rule.Step(escalation.Step)
+			ruleStatus.Escalations = append(ruleStatus.Escalations, control.Escalation{
+				Time:        escalation.Time,
+				Step:        escalation.Step,
+				Urgency:     step.Urgency.String(),
+				Action:      step.Action,
+				WindowTitle: escalation.Window.Title,
+			})
+		}
+		status.Rules = append(status.Rules, ruleStatus)
+	}
+	return status
+}
+
+// Saves the bucket levels and budget usage if they changed since they were last saved.
+func (d *daemon) save(now time.Time) {
+	buckets := d.annoyer.Buckets()
+	budgets := d.annoyer.Budgets()
+	if reflect.DeepEqual(buckets, d.saved.Buckets) && reflect.DeepEqual(budgets, d.saved.Budgets) {
+		return
+	}
+	d.saved = state.State{SavedAt: now, Buckets: buckets, Budgets: budgets}
+	if err := state.Save(d.options.StatePath, d.saved); err != nil {
+		fmt.Printf("Unable to save the state: %v\n", err)
+	}
+}
+
+// Logs the sample that ended at now.
+func (d *daemon) record(now time.Time, duration time.Duration, window xscan.Window, rule string) {
+	if duration <= 0 {
+		return
+	}
+	err := d.tracker.Record(track.Sample{
+		Time:            now.Add(-duration),
+		Duration:        duration,
+		ApplicationName: window.ApplicationName,
+		Title:           window.Title,
+		Rule:            rule,
+		Project:         window.Project,
+	})
+	if err != nil {
+		fmt.Printf("Unable to record a sample: %v\n", err)
+	}
+}
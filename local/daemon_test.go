@@ -0,0 +1,149 @@
+package local
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dwetterau/glider/local/annoy"
+	"github.com/dwetterau/glider/local/calendar"
+	"github.com/dwetterau/glider/local/clock"
+	"github.com/dwetterau/glider/local/config"
+	"github.com/dwetterau/glider/local/idle"
+	"github.com/dwetterau/glider/local/notify"
+	"github.com/dwetterau/glider/local/track"
+	"github.com/dwetterau/glider/local/xscan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeetingProfile(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2018, time.September, 3, hour, minute, 0, 0, time.Local)
+	}
+	slack := annoy.Rule{Name: "slack", ApplicationName: "Slack", BucketSize: 3 * time.Minute, DrainFactor: 1}
+	mail := annoy.Rule{
+		Name: "mail", ApplicationName: "Thunderbird", Budget: annoy.Budget{Daily: time.Hour, Warnings: []float64{50}},
+	}
+	quietSlack := slack
+	quietSlack.BucketSize = 15 * time.Minute
+	c := clock.NewFake(at(9, 0))
+	notifiers := map[string]notify.Notifier{annoy.DefaultNotifier: &notify.Recorder{}}
+	d := &daemon{
+		annoyer:   annoy.NewAnnoyerWithClock([]annoy.Rule{slack, mail}, notifiers, nil, c),
+		rules:     []annoy.Rule{slack, mail},
+		profiles:  map[string][]annoy.Rule{"meetings": {quietSlack}},
+		notifiers: notifiers,
+		calendar:  config.Calendar{Profile: "meetings"},
+		meetings: []calendar.Occurrence{
+			{Event: &calendar.Event{Summary: "Standup"}, Start: at(10, 0), End: at(10, 30)},
+		},
+	}
+
+	d.updateMeeting(at(9, 0))
+	d.annoyer.MaybeAnnoy(xscan.Window{ApplicationName: "Thunderbird"}, 40*time.Minute)
+	d.annoyer.MaybeAnnoy(xscan.Window{ApplicationName: "Slack"}, 2*time.Minute)
+	before := d.annoyer.Budgets()["mail"]
+	require.Equal(t, 40*time.Minute, before.Used)
+
+	// The meeting profile takes over, but what the usual rules used is kept, and saved.
+	c.Set(at(10, 0))
+	d.updateMeeting(at(10, 0))
+	require.Len(t, d.annoyer.Rules(), 1)
+	assert.Equal(t, 15*time.Minute, d.annoyer.Rules()[0].BucketSize)
+	assert.Equal(t, 2*time.Minute, d.annoyer.Buckets()["slack"])
+	assert.Equal(t, before, d.annoyer.Budgets()["mail"])
+
+	// Afterwards the budget picks up where it left off instead of starting over.
+	c.Set(at(10, 30))
+	d.updateMeeting(at(10, 30))
+	require.Len(t, d.annoyer.Rules(), 2)
+	assert.Equal(t, before, d.annoyer.Budgets()["mail"])
+	assert.Equal(t, 2*time.Minute, d.annoyer.Buckets()["slack"])
+
+	// Rules that are gone from the config are forgotten for good, even during a meeting.
+	c.Set(at(11, 0))
+	d.meetings = append(d.meetings, calendar.Occurrence{
+		Event: &calendar.Event{Summary: "Planning"}, Start: at(11, 0), End: at(12, 0),
+	})
+	d.updateMeeting(at(11, 0))
+	d.rules = []annoy.Rule{slack}
+	d.setRules()
+	d.forgetRemovedRules()
+	_, ok := d.annoyer.Budgets()["mail"]
+	assert.False(t, ok)
+	d.rules = []annoy.Rule{slack, mail}
+	c.Set(at(12, 0))
+	d.updateMeeting(at(12, 0))
+	assert.Equal(t, time.Duration(0), d.annoyer.Budgets()["mail"].Used)
+}
+
+func TestNextEndOfDay(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	endOfDay := 17*time.Hour + 30*time.Minute
+	assert.Equal(
+		t,
+		time.Date(2018, time.March, 11, 17, 30, 0, 0, newYork),
+		nextEndOfDay(time.Date(2018, time.March, 11, 9, 0, 0, 0, newYork), endOfDay),
+	)
+	assert.Equal(
+		t,
+		time.Date(2018, time.November, 4, 17, 30, 0, 0, newYork),
+		nextEndOfDay(time.Date(2018, time.November, 3, 18, 0, 0, 0, newYork), endOfDay),
+	)
+	assert.Equal(t, time.Time{}, nextEndOfDay(time.Date(2018, time.November, 3, 18, 0, 0, 0, newYork), -1))
+}
+
+// Always away from the keyboard.
+type awaySource struct{}
+
+func (awaySource) IdleTime() (time.Duration, error) {
+	return time.Hour, nil
+}
+
+func TestIdleWhilePaused(t *testing.T) {
+	dir, err := ioutil.TempDir("", "daemon_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	tracker, err := track.NewSQLite(filepath.Join(dir, "track.db"))
+	require.NoError(t, err)
+	start := time.Date(2018, time.September, 3, 9, 0, 0, 0, time.Local)
+	slack := annoy.Rule{Name: "slack", ApplicationName: "Slack", BucketSize: 3 * time.Minute, DrainFactor: 1}
+	notifiers := map[string]notify.Notifier{annoy.DefaultNotifier: &notify.Recorder{}}
+	d := &daemon{
+		options:     Options{StatePath: filepath.Join(dir, "state.json")},
+		idle:        idle.NewDetector(awaySource{}, 5*time.Minute),
+		tracker:     tracker,
+		annoyer:     annoy.NewAnnoyerWithClock([]annoy.Rule{slack}, notifiers, nil, clock.NewFake(start)),
+		rules:       []annoy.Rule{slack},
+		notifiers:   notifiers,
+		lastCharged: start,
+	}
+	d.annoyer.MaybeAnnoy(xscan.Window{ApplicationName: "Slack"}, 2*time.Minute)
+
+	// Pausing during a presentation leaves the buckets alone, however long it goes.
+	d.paused = true
+	d.charge(start.Add(10 * time.Minute))
+	assert.Equal(t, 2*time.Minute, d.annoyer.Buckets()["slack"])
+	samples, err := tracker.Samples(start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, samples, 1)
+	assert.Equal(t, xscan.IdleWindow.ApplicationName, samples[0].ApplicationName)
+
+	// So do meetings, where the keyboard usually sits idle.
+	d.paused = false
+	d.calendar.Paths = []string{filepath.Join(dir, "work.ics")}
+	require.NoError(t, ioutil.WriteFile(d.calendar.Paths[0], []byte(
+		"BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:1\nSUMMARY:Standup\n"+
+			"CATEGORIES:Meeting\nDTSTART:20180903T091000\nDTEND:20180903T094000\nEND:VEVENT\nEND:VCALENDAR\n",
+	), 0600))
+	d.charge(start.Add(30 * time.Minute))
+	assert.Equal(t, 2*time.Minute, d.annoyer.Buckets()["slack"])
+
+	// Once it's over, time away drains the buckets as usual.
+	d.charge(start.Add(41 * time.Minute))
+	assert.Equal(t, time.Duration(0), d.annoyer.Buckets()["slack"])
+}
@@ -0,0 +1,184 @@
+package enforce
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/dwetterau/glider/local/xscan"
+)
+
+// Actions that can be taken on an offending window.
+const (
+	ActionMinimize = "minimize"
+	// Switches focus to a window of the target application.
+	ActionFocus = "focus"
+	// Politely asks the window to close, like clicking its close button.
+	ActionClose = "close"
+)
+
+// Windows of these applications are never acted on, on top of the configured allowlist.
+// Entries are matched case insensitively and can use path.Match wildcards.
+var DefaultAllowlist = []string{
+	"alacritty", "foot", "gnome-terminal*", "kitty", "konsole", "terminator", "tilix",
+	"urxvt", "wezterm*", "xterm", "code", "code-oss", "emacs", "gvim", "jetbrains-*",
+	"sublime_text",
+}
+
+// Does the actual work of an action on a window, for windows from one scanner backend.
+type Actor interface {
+	Minimize(window xscan.Window) error
+	Close(window xscan.Window) error
+	// Focuses a window with the given WM_CLASS, or app_id on wayland.
+	Focus(class string) error
+}
+
+type Config struct {
+	// Log what would have been done instead of doing it.
+	DryRun bool
+	// Applications never to act on, in addition to DefaultAllowlist.
+	Allowlist []string
+	// The window class focus switches to when a focus action doesn't name one.
+	WorkClass string
+}
+
+var ErrAllowlisted = errors.New("the window is allowlisted")
+
+// Takes actions on windows, as long as they aren't allowlisted.
+type Enforcer struct {
+	lock   sync.Mutex
+	config Config
+	// By the backend of the windows they act on.
+	actors    map[string]Actor
+	newActors map[string]func() (Actor, error)
+}
+
+// Acts on X11 and sway windows. The display is only connected to once the first action
+// is taken.
+func New(c Config) *Enforcer {
+	return &Enforcer{
+		config: c,
+		actors: make(map[string]Actor),
+		newActors: map[string]func() (Actor, error){
+			xscan.BackendXdotool: NewX11,
+			xscan.BackendX11:     NewX11,
+			xscan.BackendSway:    NewSway,
+		},
+	}
+}
+
+// Acts on windows with the actor for the backend that found them.
+func NewWithActors(c Config, actors map[string]Actor) *Enforcer {
+	return &Enforcer{config: c, actors: actors}
+}
+
+// Swaps in a new config, e.g. after the config file changed.
+func (e *Enforcer) SetConfig(c Config) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.config = c
+}
+
+// Checks that the action exists and has what it needs to run.
+func Validate(c Config, action, target string) error {
+	switch action {
+	case ActionMinimize, ActionClose:
+		return nil
+	case ActionFocus:
+		if target == "" && c.WorkClass == "" {
+			return errors.New("focus needs a target or an [enforce] work_class")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown action %q, use minimize, focus or close", action)
+	}
+}
+
+// Takes the action on the window. Focus switches to target, or to the work application if
+// target is empty. Allowlisted windows are left alone and ErrAllowlisted is returned.
+func (e *Enforcer) Do(action, target string, dryRun bool, window xscan.Window) error {
+	e.lock.Lock()
+	c := e.config
+	e.lock.Unlock()
+	if allowlisted(c, window) {
+		return ErrAllowlisted
+	}
+	if action == ActionFocus && target == "" {
+		target = c.WorkClass
+	}
+	if err := Validate(c, action, target); err != nil {
+		return err
+	}
+	if dryRun || c.DryRun {
+		if action == ActionFocus {
+			fmt.Printf("Dry run: would switch from %q to %s\n", window.Title, target)
+		} else {
+			fmt.Printf("Dry run: would %s %q\n", action, window.Title)
+		}
+		return nil
+	}
+	actor, err := e.getActor(window)
+	if err != nil {
+		return err
+	}
+	switch action {
+	case ActionMinimize:
+		return actor.Minimize(window)
+	case ActionClose:
+		return actor.Close(window)
+	default:
+		return actor.Focus(target)
+	}
+}
+
+func (e *Enforcer) Allowlisted(window xscan.Window) bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return allowlisted(e.config, window)
+}
+
+func allowlisted(c Config, window xscan.Window) bool {
+	return OnAllowlist(c.Allowlist, window)
+}
+
+// Reports whether the window's application is on DefaultAllowlist or the given one. A
+// window without an application name always is, since there's no telling what it is.
+func OnAllowlist(allowlist []string, window xscan.Window) bool {
+	name := strings.ToLower(window.ApplicationName)
+	if name == "" {
+		return true
+	}
+	for _, list := range [][]string{DefaultAllowlist, allowlist} {
+		for _, pattern := range list {
+			if matched, _ := path.Match(strings.ToLower(pattern), name); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Window IDs only mean something to the backend that found the window, so windows are
+// only acted on by that backend's actor.
+func (e *Enforcer) getActor(window xscan.Window) (Actor, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if actor, ok := e.actors[window.Backend]; ok {
+		return actor, nil
+	}
+	newActor, ok := e.newActors[window.Backend]
+	if !ok {
+		if window.Backend == "" {
+			return nil, fmt.Errorf("%q didn't come from a scanner, there's nothing to act on", window.Title)
+		}
+		return nil, fmt.Errorf("enforcement actions don't work on %s windows", window.Backend)
+	}
+	actor, err := newActor()
+	if err != nil {
+		return nil, fmt.Errorf("enforcement actions need %s: %v", window.Backend, err)
+	}
+	e.actors[window.Backend] = actor
+	return actor, nil
+}
@@ -0,0 +1,122 @@
+package enforce
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dwetterau/glider/local/xscan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeActor struct {
+	actions []string
+}
+
+func (f *fakeActor) Minimize(window xscan.Window) error {
+	f.actions = append(f.actions, "minimize "+window.Title)
+	return nil
+}
+
+func (f *fakeActor) Close(window xscan.Window) error {
+	f.actions = append(f.actions, "close "+window.Title)
+	return nil
+}
+
+func (f *fakeActor) Focus(class string) error {
+	f.actions = append(f.actions, "focus "+class)
+	return nil
+}
+
+var slack = xscan.Window{
+	ApplicationName: "Slack", Title: "general | Team Slack", WindowID: "42", Backend: xscan.BackendX11,
+}
+
+func TestDo(t *testing.T) {
+	actor := &fakeActor{}
+	e := NewWithActors(Config{WorkClass: "Code"}, map[string]Actor{xscan.BackendX11: actor})
+
+	require.NoError(t, e.Do(ActionMinimize, "", false, slack))
+	require.NoError(t, e.Do(ActionClose, "", false, slack))
+	require.NoError(t, e.Do(ActionFocus, "", false, slack))
+	require.NoError(t, e.Do(ActionFocus, "Emacs", false, slack))
+	// Dry runs only log.
+	require.NoError(t, e.Do(ActionClose, "", true, slack))
+	assert.Error(t, e.Do("explode", "", false, slack))
+
+	assert.Equal(t, []string{
+		"minimize general | Team Slack",
+		"close general | Team Slack",
+		"focus Code",
+		"focus Emacs",
+	}, actor.actions)
+}
+
+func TestDryRunConfig(t *testing.T) {
+	actor := &fakeActor{}
+	e := NewWithActors(Config{DryRun: true}, map[string]Actor{xscan.BackendX11: actor})
+	require.NoError(t, e.Do(ActionMinimize, "", false, slack))
+	assert.Empty(t, actor.actions)
+
+	e.SetConfig(Config{})
+	require.NoError(t, e.Do(ActionMinimize, "", false, slack))
+	assert.Equal(t, []string{"minimize general | Team Slack"}, actor.actions)
+}
+
+func TestAllowlist(t *testing.T) {
+	actor := &fakeActor{}
+	e := NewWithActors(Config{Allowlist: []string{"Firefox"}}, map[string]Actor{xscan.BackendX11: actor})
+
+	for _, name := range []string{"Gnome-terminal", "Code", "jetbrains-goland", "firefox", ""} {
+		window := xscan.Window{ApplicationName: name, Title: name}
+		assert.True(t, e.Allowlisted(window), name)
+		assert.Equal(t, ErrAllowlisted, e.Do(ActionClose, "", false, window))
+	}
+	assert.False(t, e.Allowlisted(slack))
+	assert.Empty(t, actor.actions)
+}
+
+func TestBackends(t *testing.T) {
+	x11 := &fakeActor{}
+	e := NewWithActors(Config{}, map[string]Actor{xscan.BackendX11: x11})
+
+	// A sway container id means nothing to X11.
+	swaySlack := slack
+	swaySlack.Backend = xscan.BackendSway
+	assert.EqualError(t, e.Do(ActionClose, "", false, swaySlack), "enforcement actions don't work on sway windows")
+	replayed := slack
+	replayed.Backend = ""
+	assert.Error(t, e.Do(ActionClose, "", false, replayed))
+	assert.Empty(t, x11.actions)
+
+	var commands []string
+	sway := swayActor{command: func(command string) error {
+		commands = append(commands, command)
+		if command == `[app_id="(?i)^org[.]gnome[.]Nautilus$"] focus` {
+			return errors.New("No matching node.")
+		}
+		return nil
+	}}
+	e = NewWithActors(Config{}, map[string]Actor{xscan.BackendX11: x11, xscan.BackendSway: sway})
+	require.NoError(t, e.Do(ActionMinimize, "", false, swaySlack))
+	require.NoError(t, e.Do(ActionClose, "", false, swaySlack))
+	require.NoError(t, e.Do(ActionFocus, "code-oss", false, swaySlack))
+	require.NoError(t, e.Do(ActionFocus, "org.gnome.Nautilus", false, swaySlack))
+	assert.Equal(t, []string{
+		"[con_id=42] move scratchpad",
+		"[con_id=42] kill",
+		`[app_id="(?i)^code-oss$"] focus`,
+		`[app_id="(?i)^org[.]gnome[.]Nautilus$"] focus`,
+		`[class="(?i)^org[.]gnome[.]Nautilus$"] focus`,
+	}, commands)
+	assert.Error(t, sway.Close(slack))
+	assert.Empty(t, x11.actions)
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(Config{}, ActionMinimize, ""))
+	assert.NoError(t, Validate(Config{}, ActionFocus, "Code"))
+	assert.Error(t, Validate(Config{}, ActionFocus, ""))
+	assert.NoError(t, Validate(Config{WorkClass: "Code"}, ActionFocus, ""))
+	assert.Error(t, Validate(Config{}, "explode", ""))
+}
@@ -0,0 +1,82 @@
+package enforce
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/dwetterau/glider/local/xscan"
+)
+
+// Acts on windows with sway commands over its IPC socket.
+type swayActor struct {
+	command func(command string) error
+}
+
+func NewSway() (Actor, error) {
+	if os.Getenv("SWAYSOCK") == "" {
+		return nil, errors.New("SWAYSOCK is not set, is sway running?")
+	}
+	return swayActor{command: xscan.SwayCommand}, nil
+}
+
+// Sway has no minimizing, the scratchpad is the closest thing to it.
+func (s swayActor) Minimize(window xscan.Window) error {
+	criteria, err := conID(window)
+	if err != nil {
+		return err
+	}
+	return s.command(criteria + " move scratchpad")
+}
+
+func (s swayActor) Close(window xscan.Window) error {
+	criteria, err := conID(window)
+	if err != nil {
+		return err
+	}
+	return s.command(criteria + " kill")
+}
+
+// Native wayland windows are matched by app_id, xwayland ones by their X11 class.
+func (s swayActor) Focus(class string) error {
+	pattern, err := swayPattern(class)
+	if err != nil {
+		return err
+	}
+	if err := s.command(fmt.Sprintf(`[app_id="%s"] focus`, pattern)); err == nil {
+		return nil
+	}
+	if err := s.command(fmt.Sprintf(`[class="%s"] focus`, pattern)); err != nil {
+		return fmt.Errorf("there's no %s window to switch to", class)
+	}
+	return nil
+}
+
+func conID(window xscan.Window) (string, error) {
+	if window.Backend != xscan.BackendSway {
+		return "", fmt.Errorf("%q isn't a sway window", window.Title)
+	}
+	id, err := strconv.ParseInt(window.WindowID, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("%q has no sway container id", window.Title)
+	}
+	return fmt.Sprintf("[con_id=%d]", id), nil
+}
+
+// Criteria values are quoted regular expressions. Rather than get escaping right for
+// both, anything but letters, digits, - and _ is matched with a character class.
+func swayPattern(class string) (string, error) {
+	pattern := "(?i)^"
+	for _, r := range class {
+		switch {
+		case r == '"' || r == '\\' || r == '[' || r == ']' || r == '^':
+			return "", fmt.Errorf("%q can't be focused on sway", class)
+		case r == '-' || r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z':
+			pattern += string(r)
+		default:
+			pattern += "[" + string(r) + "]"
+		}
+	}
+	return pattern + "$", nil
+}
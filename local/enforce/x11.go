@@ -0,0 +1,143 @@
+package enforce
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/dwetterau/glider/local/xscan"
+)
+
+// Asks the window manager to act on windows with client messages, as described by the
+// ICCCM and EWMH.
+type x11Actor struct {
+	conn *xgb.Conn
+	root xproto.Window
+}
+
+// ICCCM's value for a minimized window.
+const iconicState = 3
+
+// EWMH's source indication for requests from pagers and the like.
+const sourcePager = 2
+
+func NewX11() (Actor, error) {
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return nil, err
+	}
+	return &x11Actor{conn: conn, root: xproto.Setup(conn).DefaultScreen(conn).Root}, nil
+}
+
+func (x *x11Actor) Minimize(window xscan.Window) error {
+	id, err := windowID(window)
+	if err != nil {
+		return err
+	}
+	return x.sendToRoot(id, "WM_CHANGE_STATE", iconicState)
+}
+
+func (x *x11Actor) Close(window xscan.Window) error {
+	id, err := windowID(window)
+	if err != nil {
+		return err
+	}
+	protocols, err := x.atom("WM_PROTOCOLS")
+	if err != nil {
+		return err
+	}
+	deleteWindow, err := x.atom("WM_DELETE_WINDOW")
+	if err != nil {
+		return err
+	}
+	supported, err := x.property(id, protocols, xproto.AtomAtom)
+	if err != nil {
+		return err
+	}
+	for i := 0; i+4 <= len(supported); i += 4 {
+		if xproto.Atom(xgb.Get32(supported[i:])) == deleteWindow {
+			event := xproto.ClientMessageEvent{
+				Format: 32,
+				Window: id,
+				Type:   protocols,
+				Data: xproto.ClientMessageDataUnionData32New(
+					[]uint32{uint32(deleteWindow), xproto.TimeCurrentTime, 0, 0, 0},
+				),
+			}
+			return xproto.SendEventChecked(x.conn, false, id, xproto.EventMaskNoEvent, string(event.Bytes())).Check()
+		}
+	}
+	return fmt.Errorf("%q doesn't support WM_DELETE_WINDOW", window.Title)
+}
+
+func (x *x11Actor) Focus(class string) error {
+	clientList, err := x.atom("_NET_CLIENT_LIST")
+	if err != nil {
+		return err
+	}
+	clients, err := x.property(x.root, clientList, xproto.AtomWindow)
+	if err != nil {
+		return err
+	}
+	for i := 0; i+4 <= len(clients); i += 4 {
+		id := xproto.Window(xgb.Get32(clients[i:]))
+		wmClass, err := x.property(id, xproto.AtomWmClass, xproto.AtomString)
+		if err != nil {
+			continue
+		}
+		// WM_CLASS is the instance name and then the class name, either one will do.
+		for _, name := range bytes.Split(bytes.TrimRight(wmClass, "\x00"), []byte{0}) {
+			if strings.EqualFold(string(name), class) {
+				return x.sendToRoot(id, "_NET_ACTIVE_WINDOW", sourcePager, xproto.TimeCurrentTime)
+			}
+		}
+	}
+	return fmt.Errorf("there's no %s window to switch to", class)
+}
+
+// Sends a client message about the window to the root window, where the window manager
+// listens for them.
+func (x *x11Actor) sendToRoot(id xproto.Window, messageType string, data ...uint32) error {
+	atom, err := x.atom(messageType)
+	if err != nil {
+		return err
+	}
+	event := xproto.ClientMessageEvent{
+		Format: 32,
+		Window: id,
+		Type:   atom,
+		Data:   xproto.ClientMessageDataUnionData32New(append(data, make([]uint32, 5-len(data))...)),
+	}
+	mask := uint32(xproto.EventMaskSubstructureRedirect | xproto.EventMaskSubstructureNotify)
+	return xproto.SendEventChecked(x.conn, false, x.root, mask, string(event.Bytes())).Check()
+}
+
+func (x *x11Actor) atom(name string) (xproto.Atom, error) {
+	reply, err := xproto.InternAtom(x.conn, false, uint16(len(name)), name).Reply()
+	if err != nil {
+		return 0, err
+	}
+	return reply.Atom, nil
+}
+
+func (x *x11Actor) property(id xproto.Window, atom, kind xproto.Atom) ([]byte, error) {
+	reply, err := xproto.GetProperty(x.conn, false, id, atom, kind, 0, 1<<16).Reply()
+	if err != nil {
+		return nil, err
+	}
+	return reply.Value, nil
+}
+
+func windowID(window xscan.Window) (xproto.Window, error) {
+	if window.Backend != xscan.BackendX11 && window.Backend != xscan.BackendXdotool {
+		return 0, fmt.Errorf("%q isn't an X11 window", window.Title)
+	}
+	id, err := strconv.ParseUint(window.WindowID, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%q isn't an X11 window", window.Title)
+	}
+	return xproto.Window(id), nil
+}
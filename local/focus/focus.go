@@ -0,0 +1,172 @@
+package focus
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dwetterau/glider/local/enforce"
+	"github.com/dwetterau/glider/local/xscan"
+)
+
+type Config struct {
+	// Applications that count as work during a session, on top of
+	// enforce.DefaultAllowlist. Every other application is a distraction.
+	Allowlist []string
+	// How long the break after a session is. Zero means there's no break.
+	Break time.Duration
+	// How often to nag while a distracting application has focus.
+	Repeat time.Duration
+	// The names of the notifiers to send notifications to. Defaults to the default
+	// notifier of the annoyer rules, or every notifier if that isn't configured.
+	Notifiers []string
+}
+
+func DefaultConfig() Config {
+	return Config{Break: 10 * time.Minute, Repeat: time.Minute}
+}
+
+// The phases a session goes through, in order.
+const (
+	PhaseFocus = "focus"
+	PhaseBreak = "break"
+	PhaseDone  = "done"
+)
+
+// A stretch of focused work followed by a break. Distracting applications have zero
+// tolerance during the focus phase: they're nagged about as soon as they get focus.
+type Session struct {
+	config Config
+
+	Start       time.Time
+	Length      time.Duration
+	BreakLength time.Duration
+	Phase       string
+	// When the focus phase ended, early or not.
+	BreakStart time.Time
+	// Time spent on allowlisted and on distracting applications during the focus phase.
+	Focused, Distracted time.Duration
+	// The time spent on each distracting application.
+	Distractions map[string]time.Duration
+
+	lastNag time.Time
+}
+
+func Start(c Config, now time.Time, length, breakLength time.Duration) *Session {
+	return &Session{
+		config:       c,
+		Start:        now,
+		Length:       length,
+		BreakLength:  breakLength,
+		Phase:        PhaseFocus,
+		Distractions: make(map[string]time.Duration),
+	}
+}
+
+func (s *Session) End() time.Time {
+	return s.Start.Add(s.Length)
+}
+
+func (s *Session) BreakEnd() time.Time {
+	return s.BreakStart.Add(s.BreakLength)
+}
+
+// Counts the time up until now that was spent on the window. Returns true if it's time to
+// nag about a distraction.
+func (s *Session) Charge(now time.Time, window xscan.Window, duration time.Duration) bool {
+	if s.Phase != PhaseFocus || window == xscan.IdleWindow {
+		return false
+	}
+	// Only the part of the sample before the session ended counts.
+	if over := now.Sub(s.End()); over > 0 {
+		duration -= over
+	}
+	if duration <= 0 {
+		return false
+	}
+	if enforce.OnAllowlist(s.config.Allowlist, window) {
+		s.Focused += duration
+		// Going back to work means the next distraction gets nagged about right away.
+		s.lastNag = time.Time{}
+		return false
+	}
+	s.Distracted += duration
+	s.Distractions[window.ApplicationName] += duration
+	if !s.lastNag.IsZero() && now.Sub(s.lastNag) < s.config.Repeat {
+		return false
+	}
+	s.lastNag = now
+	return true
+}
+
+// Moves the session into the phase it should be in at now. Returns the new phase, or ""
+// if the phase didn't change.
+func (s *Session) Advance(now time.Time) string {
+	switch {
+	case s.Phase == PhaseFocus && !now.Before(s.End()):
+		s.BreakStart = s.End()
+		s.Phase = PhaseBreak
+		if s.BreakLength <= 0 || !now.Before(s.BreakEnd()) {
+			s.Phase = PhaseDone
+		}
+		return s.Phase
+	case s.Phase == PhaseBreak && !now.Before(s.BreakEnd()):
+		s.Phase = PhaseDone
+		return s.Phase
+	}
+	return ""
+}
+
+// Ends the session at now, wherever it is.
+func (s *Session) Stop(now time.Time) {
+	switch s.Phase {
+	case PhaseFocus:
+		s.BreakStart = now
+		s.BreakLength = 0
+	case PhaseBreak:
+		s.BreakLength = now.Sub(s.BreakStart)
+	}
+	s.Phase = PhaseDone
+}
+
+// Reports whether the whole focus phase was worked through.
+func (s *Session) Completed() bool {
+	return s.Phase != PhaseFocus && !s.BreakStart.Before(s.End())
+}
+
+func (s *Session) Nag(now time.Time) string {
+	return fmt.Sprintf(
+		"You're in a focus session for another %v, get back to work.", s.End().Sub(now).Round(time.Minute),
+	)
+}
+
+// Describes how the focus phase went, for when it's over.
+func (s *Session) Summary() string {
+	summary := fmt.Sprintf(
+		"Focus session over: %v focused, %v distracted",
+		s.Focused.Round(time.Second), s.Distracted.Round(time.Second),
+	)
+	if len(s.Distractions) > 0 {
+		names := make([]string, 0, len(s.Distractions))
+		for name := range s.Distractions {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			if s.Distractions[names[i]] != s.Distractions[names[j]] {
+				return s.Distractions[names[i]] > s.Distractions[names[j]]
+			}
+			return names[i] < names[j]
+		})
+		parts := make([]string, len(names))
+		for i, name := range names {
+			parts[i] = fmt.Sprintf("%s %v", name, s.Distractions[name].Round(time.Second))
+		}
+		summary += " (" + strings.Join(parts, ", ") + ")"
+	}
+	summary += "."
+	if s.Phase == PhaseBreak {
+		summary += fmt.Sprintf(" Take a %v break.", s.BreakLength)
+	}
+	return summary
+}
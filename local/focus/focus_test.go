@@ -0,0 +1,65 @@
+package focus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dwetterau/glider/local/xscan"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSession(t *testing.T) {
+	start := time.Date(2018, 9, 3, 9, 0, 0, 0, time.Local)
+	s := Start(Config{Allowlist: []string{"Firefox"}, Repeat: time.Minute}, start, 50*time.Minute, 10*time.Minute)
+	code := xscan.Window{ApplicationName: "Code"}
+	firefox := xscan.Window{ApplicationName: "Firefox"}
+	slack := xscan.Window{ApplicationName: "Slack"}
+
+	now := start.Add(20 * time.Minute)
+	assert.False(t, s.Charge(now, code, 20*time.Minute))
+	assert.Equal(t, "", s.Advance(now))
+
+	// Distractions are nagged about right away, and then every Repeat.
+	now = now.Add(5 * time.Second)
+	assert.True(t, s.Charge(now, slack, 5*time.Second))
+	now = now.Add(30 * time.Second)
+	assert.False(t, s.Charge(now, slack, 30*time.Second))
+	now = now.Add(30 * time.Second)
+	assert.True(t, s.Charge(now, slack, 30*time.Second))
+	assert.Equal(t, "You're in a focus session for another 29m0s, get back to work.", s.Nag(now))
+	now = now.Add(10 * time.Minute)
+	assert.False(t, s.Charge(now, firefox, 10*time.Minute))
+	now = now.Add(time.Minute)
+	assert.True(t, s.Charge(now, slack, time.Minute))
+
+	// Only the time up to the end of the session counts.
+	now = start.Add(55 * time.Minute)
+	assert.False(t, s.Charge(now, code, now.Sub(start.Add(32*time.Minute+5*time.Second))))
+	assert.Equal(t, PhaseBreak, s.Advance(now))
+	assert.Equal(t, 47*time.Minute+55*time.Second, s.Focused)
+	assert.Equal(t, 2*time.Minute+5*time.Second, s.Distracted)
+	assert.Equal(
+		t,
+		"Focus session over: 47m55s focused, 2m5s distracted (Slack 2m5s). Take a 10m0s break.",
+		s.Summary(),
+	)
+	assert.False(t, s.Charge(now.Add(time.Minute), slack, time.Minute))
+
+	assert.Equal(t, "", s.Advance(start.Add(59*time.Minute)))
+	assert.Equal(t, PhaseDone, s.Advance(start.Add(time.Hour)))
+	assert.True(t, s.Completed())
+}
+
+func TestStop(t *testing.T) {
+	start := time.Date(2018, 9, 3, 9, 0, 0, 0, time.Local)
+	s := Start(DefaultConfig(), start, 25*time.Minute, 5*time.Minute)
+	s.Stop(start.Add(10 * time.Minute))
+	assert.Equal(t, PhaseDone, s.Phase)
+	assert.False(t, s.Completed())
+
+	s = Start(DefaultConfig(), start, 25*time.Minute, 5*time.Minute)
+	s.Advance(start.Add(25 * time.Minute))
+	s.Stop(start.Add(27 * time.Minute))
+	assert.True(t, s.Completed())
+	assert.Equal(t, 2*time.Minute, s.BreakLength)
+}
@@ -0,0 +1,216 @@
+package idle
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/screensaver"
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/dwetterau/glider/local/clock"
+	"github.com/godbus/dbus"
+)
+
+// Reports how long it has been since the user last touched the keyboard or mouse.
+type Source interface {
+	IdleTime() (time.Duration, error)
+}
+
+// Names of the idle sources that can be selected at startup.
+const (
+	SourceAuto         = "auto"
+	SourceXScreenSaver = "xscreensaver"
+	SourceLogind       = "logind"
+	SourceNone         = "none"
+)
+
+// Returns the idle source with the given name. "auto" uses the XScreenSaver extension
+// under X11 and logind everywhere else.
+func New(name string) (Source, error) {
+	if name == SourceAuto {
+		name = SourceLogind
+		if os.Getenv("DISPLAY") != "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+			name = SourceXScreenSaver
+		}
+	}
+	switch name {
+	case SourceXScreenSaver:
+		return NewXScreenSaver()
+	case SourceLogind:
+		return NewLogind()
+	case SourceNone:
+		return never{}, nil
+	default:
+		return nil, fmt.Errorf("unknown idle source %q", name)
+	}
+}
+
+type xScreenSaverSource struct {
+	// Returns the milliseconds since the last input.
+	query func() (uint32, error)
+}
+
+// Asks the X server's MIT-SCREEN-SAVER extension how long there has been no input.
+func NewXScreenSaver() (Source, error) {
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return nil, err
+	}
+	err = screensaver.Init(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	root := xproto.Setup(conn).DefaultScreen(conn).Root
+	return xScreenSaverSource{query: func() (uint32, error) {
+		info, err := screensaver.QueryInfo(conn, xproto.Drawable(root)).Reply()
+		if err != nil {
+			return 0, err
+		}
+		return info.MsSinceUserInput, nil
+	}}, nil
+}
+
+func (s xScreenSaverSource) IdleTime() (time.Duration, error) {
+	ms, err := s.query()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+const (
+	login1Name    = "org.freedesktop.login1"
+	login1Session = "org.freedesktop.login1.Session"
+)
+
+// The part of a dbus.BusObject that's needed to read logind's hints.
+type propertyGetter interface {
+	GetProperty(name string) (dbus.Variant, error)
+}
+
+type logindSource struct {
+	clock  clock.Clock
+	object func(path dbus.ObjectPath) propertyGetter
+	// The session the hints are read from, looked up again after it stops answering.
+	session propertyGetter
+}
+
+// Reads the IdleHint of the current session from systemd-logind. The hint is only set
+// once the desktop environment decides the session is idle, so this is coarser than
+// asking the X server.
+func NewLogind() (Source, error) {
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return nil, err
+	}
+	return &logindSource{
+		clock: clock.Real{},
+		object: func(path dbus.ObjectPath) propertyGetter {
+			return conn.Object(login1Name, path)
+		},
+	}, nil
+}
+
+func (s *logindSource) IdleTime() (time.Duration, error) {
+	if s.session == nil {
+		session, err := s.findSession()
+		if err != nil {
+			return 0, err
+		}
+		s.session = session
+	}
+	idleTime, err := s.idleTime()
+	if err != nil {
+		s.session = nil
+	}
+	return idleTime, err
+}
+
+// Returns the session glider runs in. Outside of one, e.g. as a systemd user service,
+// that's the user's graphical session instead.
+func (s *logindSource) findSession() (propertyGetter, error) {
+	auto := s.object("/org/freedesktop/login1/session/auto")
+	if _, err := auto.GetProperty(login1Session + ".Id"); err == nil {
+		return auto, nil
+	}
+	display, err := s.object("/org/freedesktop/login1/user/self").GetProperty("org.freedesktop.login1.User.Display")
+	if err != nil {
+		return nil, fmt.Errorf("unable to find a logind session: %v", err)
+	}
+	// The session's ID and object path.
+	fields, ok := display.Value().([]interface{})
+	if !ok || len(fields) != 2 {
+		return nil, errors.New("unexpected type for Display")
+	}
+	path, ok := fields[1].(dbus.ObjectPath)
+	if !ok {
+		return nil, errors.New("unexpected type for Display")
+	}
+	if path == "/" {
+		return nil, errors.New("there's no logind session with a display")
+	}
+	return s.object(path), nil
+}
+
+func (s *logindSource) idleTime() (time.Duration, error) {
+	hint, err := s.session.GetProperty(login1Session + ".IdleHint")
+	if err != nil {
+		return 0, err
+	}
+	idle, ok := hint.Value().(bool)
+	if !ok {
+		return 0, errors.New("unexpected type for IdleHint")
+	}
+	if !idle {
+		return 0, nil
+	}
+	since, err := s.session.GetProperty(login1Session + ".IdleSinceHint")
+	if err != nil {
+		return 0, err
+	}
+	usec, ok := since.Value().(uint64)
+	if !ok {
+		return 0, errors.New("unexpected type for IdleSinceHint")
+	}
+	return s.clock.Now().Sub(time.Unix(0, int64(usec)*int64(time.Microsecond))), nil
+}
+
+// Used when idle detection is turned off.
+type never struct{}
+
+func (never) IdleTime() (time.Duration, error) {
+	return 0, nil
+}
+
+// Decides whether the user is away from the keyboard.
+type Detector struct {
+	source    Source
+	threshold time.Duration
+	// The last error from the source, so it's only logged once rather than every sample.
+	lastError string
+}
+
+func NewDetector(source Source, threshold time.Duration) *Detector {
+	return &Detector{source: source, threshold: threshold}
+}
+
+// Reports whether there has been no input for at least the threshold. The user isn't idle
+// while the source is failing.
+func (d *Detector) Idle() bool {
+	idleTime, err := d.source.IdleTime()
+	if err != nil {
+		if err.Error() != d.lastError {
+			fmt.Printf("Unable to read the idle time: %v\n", err)
+			d.lastError = err.Error()
+		}
+		return false
+	}
+	if d.lastError != "" {
+		fmt.Println("Reading the idle time works again")
+		d.lastError = ""
+	}
+	return idleTime >= d.threshold
+}
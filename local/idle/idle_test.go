@@ -0,0 +1,145 @@
+package idle
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dwetterau/glider/local/clock"
+	"github.com/godbus/dbus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A logind object's properties. Objects that don't exist have none.
+type fakeObject map[string]interface{}
+
+func (f fakeObject) GetProperty(name string) (dbus.Variant, error) {
+	value, ok := f[name]
+	if !ok {
+		return dbus.Variant{}, errors.New("no such property " + name)
+	}
+	return dbus.MakeVariant(value), nil
+}
+
+func TestLogind(t *testing.T) {
+	now := time.Date(2018, 9, 3, 9, 0, 0, 0, time.UTC)
+	twoMinutesAgo := uint64(now.Add(-2*time.Minute).UnixNano() / int64(time.Microsecond))
+	idleSession := fakeObject{
+		"org.freedesktop.login1.Session.Id":            "2",
+		"org.freedesktop.login1.Session.IdleHint":      true,
+		"org.freedesktop.login1.Session.IdleSinceHint": twoMinutesAgo,
+	}
+	activeSession := fakeObject{
+		"org.freedesktop.login1.Session.Id":       "2",
+		"org.freedesktop.login1.Session.IdleHint": false,
+	}
+	display := func(path dbus.ObjectPath) fakeObject {
+		return fakeObject{"org.freedesktop.login1.User.Display": []interface{}{"2", path}}
+	}
+	for _, testCase := range []struct {
+		name     string
+		objects  map[dbus.ObjectPath]fakeObject
+		idleTime time.Duration
+		err      string
+	}{
+		{
+			name:    "active",
+			objects: map[dbus.ObjectPath]fakeObject{"/org/freedesktop/login1/session/auto": activeSession},
+		},
+		{
+			name:     "idle",
+			objects:  map[dbus.ObjectPath]fakeObject{"/org/freedesktop/login1/session/auto": idleSession},
+			idleTime: 2 * time.Minute,
+		},
+		{
+			name: "user service",
+			objects: map[dbus.ObjectPath]fakeObject{
+				"/org/freedesktop/login1/user/self":   display("/org/freedesktop/login1/session/_32"),
+				"/org/freedesktop/login1/session/_32": idleSession,
+			},
+			idleTime: 2 * time.Minute,
+		},
+		{
+			name:    "no display",
+			objects: map[dbus.ObjectPath]fakeObject{"/org/freedesktop/login1/user/self": display("/")},
+			err:     "there's no logind session with a display",
+		},
+		{
+			name: "no session",
+			err:  "unable to find a logind session: no such property org.freedesktop.login1.User.Display",
+		},
+		{
+			name: "bad hint",
+			objects: map[dbus.ObjectPath]fakeObject{"/org/freedesktop/login1/session/auto": {
+				"org.freedesktop.login1.Session.Id":       "2",
+				"org.freedesktop.login1.Session.IdleHint": "yes",
+			}},
+			err: "unexpected type for IdleHint",
+		},
+	} {
+		source := &logindSource{
+			clock: clock.NewFake(now),
+			object: func(path dbus.ObjectPath) propertyGetter {
+				return testCase.objects[path]
+			},
+		}
+		idleTime, err := source.IdleTime()
+		if testCase.err != "" {
+			assert.EqualError(t, err, testCase.err, testCase.name)
+			assert.Nil(t, source.session, testCase.name)
+			continue
+		}
+		require.NoError(t, err, testCase.name)
+		assert.Equal(t, testCase.idleTime, idleTime, testCase.name)
+	}
+}
+
+func TestXScreenSaver(t *testing.T) {
+	for _, testCase := range []struct {
+		ms       uint32
+		err      error
+		idleTime time.Duration
+	}{
+		{0, nil, 0},
+		{1500, nil, 1500 * time.Millisecond},
+		{0, errors.New("connection closed"), 0},
+	} {
+		source := xScreenSaverSource{query: func() (uint32, error) { return testCase.ms, testCase.err }}
+		idleTime, err := source.IdleTime()
+		assert.Equal(t, testCase.err, err)
+		assert.Equal(t, testCase.idleTime, idleTime)
+	}
+}
+
+type fakeSource struct {
+	idleTime time.Duration
+	err      error
+}
+
+func (f *fakeSource) IdleTime() (time.Duration, error) {
+	return f.idleTime, f.err
+}
+
+func TestDetector(t *testing.T) {
+	source := &fakeSource{}
+	d := NewDetector(source, 5*time.Minute)
+	for _, testCase := range []struct {
+		idleTime  time.Duration
+		err       error
+		idle      bool
+		lastError string
+	}{
+		{0, nil, false, ""},
+		{4 * time.Minute, nil, false, ""},
+		{5 * time.Minute, nil, true, ""},
+		// Failures don't count as idle, and are only logged once.
+		{time.Hour, errors.New("no session"), false, "no session"},
+		{time.Hour, errors.New("no session"), false, "no session"},
+		{time.Hour, nil, true, ""},
+	} {
+		source.idleTime, source.err = testCase.idleTime, testCase.err
+		assert.Equal(t, testCase.idle, d.Idle(), testCase.idleTime.String())
+		assert.Equal(t, testCase.lastError, d.lastError)
+	}
+}
@@ -0,0 +1,126 @@
+package notify
+
+import (
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+)
+
+const (
+	notificationsName      = "org.freedesktop.Notifications"
+	notificationsPath      = "/org/freedesktop/Notifications"
+	notificationsInterface = "org.freedesktop.Notifications"
+)
+
+// Talks to the desktop's notification server over the session bus, like notify-send.
+type dbusNotifier struct {
+	session *dbusSession
+	timeout time.Duration
+}
+
+// What every dbus notifier shares: the session bus and the callbacks of the notifications
+// that are up. It's set up once, since notifiers are built again every time the config is
+// reloaded and each one would otherwise leave a subscription and a goroutine behind.
+type dbusSession struct {
+	conn *dbus.Conn
+
+	lock      sync.Mutex
+	callbacks map[uint32]func(string)
+}
+
+var (
+	sessionLock sync.Mutex
+	session     *dbusSession
+)
+
+func NewDBus(timeout time.Duration) (Notifier, error) {
+	s, err := getSession()
+	if err != nil {
+		return nil, err
+	}
+	return &dbusNotifier{session: s, timeout: timeout}, nil
+}
+
+func getSession() (*dbusSession, error) {
+	sessionLock.Lock()
+	defer sessionLock.Unlock()
+	if session != nil {
+		return session, nil
+	}
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return nil, err
+	}
+	// Listen for button clicks so they can be passed on to OnAction.
+	call := conn.BusObject().Call(
+		"org.freedesktop.DBus.AddMatch", 0,
+		"type='signal',interface='"+notificationsInterface+"'",
+	)
+	if call.Err != nil {
+		return nil, call.Err
+	}
+	session = &dbusSession{conn: conn, callbacks: make(map[uint32]func(string))}
+	signals := make(chan *dbus.Signal, 10)
+	conn.Signal(signals)
+	go session.handleSignals(signals)
+	return session, nil
+}
+
+func (n *dbusNotifier) Notify(notification Notification) error {
+	// Actions are pairs of keys and labels, just use the label for both.
+	actions := make([]string, 0, 2*len(notification.Actions))
+	for _, action := range notification.Actions {
+		actions = append(actions, action, action)
+	}
+	hints := map[string]dbus.Variant{
+		"urgency": dbus.MakeVariant(byte(notification.Urgency)),
+	}
+	timeout := int32(-1)
+	if n.timeout > 0 {
+		timeout = int32(n.timeout / time.Millisecond)
+	}
+
+	// Hold the lock until the callback is registered so a quick click isn't missed.
+	s := n.session
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var id uint32
+	err := s.conn.Object(notificationsName, notificationsPath).Call(
+		notificationsInterface+".Notify", 0,
+		"Glider", uint32(0), "", notification.Title, notification.Body, actions, hints, timeout,
+	).Store(&id)
+	if err != nil {
+		return err
+	}
+	if notification.OnAction != nil && len(actions) > 0 {
+		s.callbacks[id] = notification.OnAction
+	}
+	return nil
+}
+
+func (s *dbusSession) handleSignals(signals <-chan *dbus.Signal) {
+	for signal := range signals {
+		if len(signal.Body) < 2 {
+			continue
+		}
+		id, ok := signal.Body[0].(uint32)
+		if !ok {
+			continue
+		}
+		switch signal.Name {
+		case notificationsInterface + ".ActionInvoked":
+			action, _ := signal.Body[1].(string)
+			s.lock.Lock()
+			callback := s.callbacks[id]
+			s.lock.Unlock()
+			if callback != nil {
+				callback(action)
+			}
+		case notificationsInterface + ".NotificationClosed":
+			s.lock.Lock()
+			delete(s.callbacks, id)
+			s.lock.Unlock()
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+type Urgency byte
+
+// The values match the urgency hint of the freedesktop notification spec.
+const (
+	Low Urgency = iota
+	Normal
+	Critical
+)
+
+func (u Urgency) String() string {
+	switch u {
+	case Low:
+		return "low"
+	case Critical:
+		return "critical"
+	default:
+		return "normal"
+	}
+}
+
+func ParseUrgency(name string) (Urgency, error) {
+	for _, u := range []Urgency{Low, Normal, Critical} {
+		if u.String() == name {
+			return u, nil
+		}
+	}
+	return Normal, fmt.Errorf("unknown urgency %q, use low, normal or critical", name)
+}
+
+type Notification struct {
+	Title   string
+	Body    string
+	Urgency Urgency
+
+	// Buttons to show with the notification, only supported by some notifiers. OnAction
+	// is called with the label of the button that was clicked.
+	Actions  []string
+	OnAction func(action string)
+}
+
+type Notifier interface {
+	Notify(n Notification) error
+}
+
+// Names of the notifier types that can be used in the config file.
+const (
+	TypeDBus     = "dbus"
+	TypeTerminal = "terminal"
+	TypeWebhook  = "webhook"
+)
+
+// How to build a notifier.
+type Config struct {
+	Type string
+	// Where webhooks are sent.
+	URL string
+	// How long desktop notifications stay up, or how long to wait for a webhook. Zero
+	// means the default.
+	Timeout time.Duration
+}
+
+func New(c Config) (Notifier, error) {
+	switch c.Type {
+	case TypeDBus:
+		return NewDBus(c.Timeout)
+	case TypeTerminal:
+		return NewTerminal(os.Stderr), nil
+	case TypeWebhook:
+		return NewWebhook(c.URL, c.Timeout), nil
+	default:
+		return nil, fmt.Errorf("unknown notifier type %q", c.Type)
+	}
+}
+
+// Builds every configured notifier, keyed by the same names as configs.
+func NewAll(configs map[string]Config) (map[string]Notifier, error) {
+	notifiers := make(map[string]Notifier, len(configs))
+	for name, c := range configs {
+		n, err := New(c)
+		if err != nil {
+			return nil, fmt.Errorf("unable to set up the %s notifier: %v", name, err)
+		}
+		notifiers[name] = n
+	}
+	return notifiers, nil
+}
+
+// Remembers every notification instead of showing it, for tests.
+type Recorder struct {
+	lock          sync.Mutex
+	notifications []Notification
+}
+
+var _ Notifier = &Recorder{}
+
+func (r *Recorder) Notify(n Notification) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.notifications = append(r.notifications, n)
+	return nil
+}
+
+func (r *Recorder) Notifications() []Notification {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]Notification(nil), r.notifications...)
+}
@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUrgency(t *testing.T) {
+	for _, u := range []Urgency{Low, Normal, Critical} {
+		parsed, err := ParseUrgency(u.String())
+		require.NoError(t, err)
+		assert.Equal(t, u, parsed)
+	}
+	_, err := ParseUrgency("urgent")
+	assert.Error(t, err)
+}
+
+func TestTerminal(t *testing.T) {
+	out := new(bytes.Buffer)
+	n := NewTerminal(out)
+	require.NoError(t, n.Notify(Notification{Title: "Glider", Body: "Stop reading Slack."}))
+	require.NoError(t, n.Notify(Notification{Title: "Glider", Body: "Seriously.", Urgency: Critical}))
+	assert.Equal(t, "\aGlider: Stop reading Slack.\n\a(!) Glider: Seriously.\n", out.String())
+}
+
+func TestWebhook(t *testing.T) {
+	var received []webhookPayload
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "POST", req.Method)
+		var payload webhookPayload
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&payload))
+		received = append(received, payload)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	n := NewWebhook(server.URL, 0)
+	require.NoError(t, n.Notify(Notification{Title: "Glider", Body: "Stop reading Slack.", Urgency: Critical}))
+	assert.Equal(t, []webhookPayload{{Title: "Glider", Body: "Stop reading Slack.", Urgency: "critical"}}, received)
+
+	status = http.StatusInternalServerError
+	assert.Error(t, n.Notify(Notification{Title: "Glider", Body: "Again"}))
+}
+
+func TestRecorder(t *testing.T) {
+	r := &Recorder{}
+	require.NoError(t, r.Notify(Notification{Body: "one"}))
+	require.NoError(t, r.Notify(Notification{Body: "two"}))
+	assert.Equal(t, []Notification{{Body: "one"}, {Body: "two"}}, r.Notifications())
+}
+
+func TestDBusSession(t *testing.T) {
+	first, err := NewDBus(0)
+	if err != nil {
+		t.Skip("there's no session bus")
+	}
+	// Reloading the config builds the notifiers again, which mustn't subscribe again.
+	second, err := NewDBus(5 * time.Second)
+	require.NoError(t, err)
+	assert.True(t, first.(*dbusNotifier).session == second.(*dbusNotifier).session)
+	assert.Equal(t, 5*time.Second, second.(*dbusNotifier).timeout)
+}
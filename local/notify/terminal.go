@@ -0,0 +1,24 @@
+package notify
+
+import (
+	"fmt"
+	"io"
+)
+
+// Rings the terminal bell and prints the notification, for when there's no desktop.
+type terminalNotifier struct {
+	out io.Writer
+}
+
+func NewTerminal(out io.Writer) Notifier {
+	return terminalNotifier{out: out}
+}
+
+func (t terminalNotifier) Notify(n Notification) error {
+	prefix := ""
+	if n.Urgency == Critical {
+		prefix = "(!) "
+	}
+	_, err := fmt.Fprintf(t.out, "\a%s%s: %s\n", prefix, n.Title, n.Body)
+	return err
+}
@@ -0,0 +1,51 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const defaultWebhookTimeout = 10 * time.Second
+
+// POSTs every notification as JSON to a URL, e.g. to get them on a phone.
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+type webhookPayload struct {
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	Urgency string `json:"urgency"`
+}
+
+func NewWebhook(url string, timeout time.Duration) Notifier {
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+	return webhookNotifier{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+func (w webhookNotifier) Notify(n Notification) error {
+	body := new(bytes.Buffer)
+	err := json.NewEncoder(body).Encode(webhookPayload{
+		Title:   n.Title,
+		Body:    n.Body,
+		Urgency: n.Urgency.String(),
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := w.client.Post(w.url, "application/json", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s responded with %s", w.url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/dwetterau/glider/local/tool"
+	"github.com/dwetterau/glider/local/xscan"
+)
+
+// How to get a project out of a window's title.
+type Extractor struct {
+	// Matches the window's application name.
+	Application *regexp.Regexp
+	// Matches the window title. The group named "project", or else the first group, is
+	// either the project's name or a path in it.
+	Title *regexp.Regexp
+}
+
+var DefaultExtractors = []Extractor{
+	{
+		// "manager.go - glider - Visual Studio Code", the project is the workspace.
+		Application: regexp.MustCompile(`(?i)^(code|code-oss|vscodium|cursor)$`),
+		Title:       regexp.MustCompile(`^(?:.* - )?(.+?) - (Visual Studio Code|VS ?Code|Code - OSS|VSCodium|Cursor)$`),
+	},
+	{
+		// "glider – manager.go", or "glider [~/src/glider] – .../manager.go" before 2020.
+		Application: regexp.MustCompile(`(?i)^jetbrains-`),
+		Title:       regexp.MustCompile(`^(.+?) (?:\[.*?\] )?[–-] `),
+	},
+	{
+		// "manager.go (glider) - Sublime Text"
+		Application: regexp.MustCompile(`(?i)^sublime_text$`),
+		Title:       regexp.MustCompile(`\(([^()]+)\) - Sublime Text`),
+	},
+	{
+		// Vim sets terminal titles like "manager.go (~/src/glider/local) - NVIM".
+		Application: regexp.MustCompile(``),
+		Title:       regexp.MustCompile(`\(([~/][^()]*)\) - N?VIM$`),
+	},
+	{
+		// Shells set terminal titles like "~/src/glider: zsh" or "me@laptop: ~/src/glider".
+		Application: regexp.MustCompile(
+			`(?i)^(alacritty|kitty|foot|footclient|gnome-terminal.*|konsole|xterm|urxvt|st|terminator|tilix|` +
+				`xfce4-terminal|wezterm.*|org\.wezfurlong\.wezterm|ghostty|com\.mitchellh\.ghostty)$`,
+		),
+		Title: regexp.MustCompile(`^(?:[\w.-]+@[\w.-]+:\s*)?(~?/[^:]*?)(?:: .*| [-—] .*)?$`),
+	},
+}
+
+type Config struct {
+	// Checked before DefaultExtractors.
+	Extractors []Extractor
+	// Directories whose subdirectories are projects, e.g. ~/src. Paths in titles that
+	// aren't under one of them belong to the git repository they're in, or else are
+	// named after their last directory.
+	Roots []string
+}
+
+// Figures out which project editor and terminal windows are working on from their
+// titles.
+type Classifier struct {
+	lock       sync.Mutex
+	extractors []Extractor
+	roots      []string
+	// Projects of paths that weren't under a root, since finding the repository takes
+	// a few stats.
+	repositories map[string]string
+}
+
+func NewClassifier(c Config) *Classifier {
+	classifier := &Classifier{}
+	classifier.SetConfig(c)
+	return classifier
+}
+
+func (c *Classifier) SetConfig(config Config) {
+	var roots []string
+	for _, root := range config.Roots {
+		roots = append(roots, filepath.Clean(tool.ExpandHome(root)))
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.extractors = append(append([]Extractor(nil), config.Extractors...), DefaultExtractors...)
+	c.roots = roots
+	c.repositories = make(map[string]string)
+}
+
+// Returns the window with its Project filled in, if one of the extractors finds one.
+func (c *Classifier) Classify(window xscan.Window) xscan.Window {
+	window.Project = c.Project(window)
+	return window
+}
+
+// Returns the window's project, or "" if it has none.
+func (c *Classifier) Project(window xscan.Window) string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, e := range c.extractors {
+		if !e.Application.MatchString(window.ApplicationName) {
+			continue
+		}
+		match := e.Title.FindStringSubmatch(window.Title)
+		if match == nil {
+			continue
+		}
+		group := 1
+		for i, name := range e.Title.SubexpNames() {
+			if name == "project" {
+				group = i
+			}
+		}
+		if group >= len(match) {
+			continue
+		}
+		name := strings.TrimSpace(match[group])
+		if strings.HasPrefix(name, "~") || strings.HasPrefix(name, "/") {
+			name = c.fromPath(name)
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// Returns the project a path is in: the directory right under a root, or the git
+// repository, or the directory itself. Home and / aren't projects.
+func (c *Classifier) fromPath(path string) string {
+	path = filepath.Clean(tool.ExpandHome(path))
+	if path == "/" || path == filepath.Clean(os.Getenv("HOME")) {
+		return ""
+	}
+	for _, root := range c.roots {
+		if relative, err := filepath.Rel(root, path); err == nil && relative != "." && !strings.HasPrefix(relative, "..") {
+			return strings.Split(relative, string(filepath.Separator))[0]
+		}
+	}
+	if project, ok := c.repositories[path]; ok {
+		return project
+	}
+	project := filepath.Base(path)
+	for dir := path; dir != "/" && dir != "."; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			project = filepath.Base(dir)
+			break
+		}
+	}
+	c.repositories[path] = project
+	return project
+}
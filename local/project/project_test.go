@@ -0,0 +1,55 @@
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/dwetterau/glider/local/xscan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProject(t *testing.T) {
+	home, err := ioutil.TempDir("", "project_test_home")
+	require.NoError(t, err)
+	defer os.RemoveAll(home)
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	os.Setenv("HOME", home)
+	require.NoError(t, os.MkdirAll(filepath.Join(home, "dotfiles", ".git"), 0700))
+	require.NoError(t, os.MkdirAll(filepath.Join(home, "dotfiles", "vim"), 0700))
+
+	c := NewClassifier(Config{
+		Extractors: []Extractor{{
+			Application: regexp.MustCompile(`^emacs$`),
+			Title:       regexp.MustCompile(`^(.+) \[(?P<project>[^\]]+)\]$`),
+		}},
+		Roots: []string{"~/src"},
+	})
+	for _, testCase := range []struct {
+		window  xscan.Window
+		project string
+	}{
+		{xscan.Window{ApplicationName: "Code", Title: "manager.go - glider - Visual Studio Code"}, "glider"},
+		{xscan.Window{ApplicationName: "code", Title: "● notes - my-repo - VS Code"}, "my-repo"},
+		{xscan.Window{ApplicationName: "Code", Title: "glider - Visual Studio Code"}, "glider"},
+		{xscan.Window{ApplicationName: "jetbrains-goland", Title: "glider – manager.go"}, "glider"},
+		{xscan.Window{ApplicationName: "sublime_text", Title: "manager.go (glider) - Sublime Text"}, "glider"},
+		{xscan.Window{ApplicationName: "Alacritty", Title: "~/src/glider: zsh"}, "glider"},
+		{xscan.Window{ApplicationName: "kitty", Title: "me@laptop: ~/src/glider/local/xscan"}, "glider"},
+		{xscan.Window{ApplicationName: "kitty", Title: "manager.go (~/src/glider/local) - NVIM"}, "glider"},
+		// Outside the roots, paths belong to their repository, or else their directory.
+		{xscan.Window{ApplicationName: "foot", Title: "~/dotfiles/vim: fish"}, "dotfiles"},
+		{xscan.Window{ApplicationName: "foot", Title: "/tmp/scratch: fish"}, "scratch"},
+		{xscan.Window{ApplicationName: "emacs", Title: "manager.go [glider]"}, "glider"},
+		// Home isn't a project, and neither is a title without a path.
+		{xscan.Window{ApplicationName: "Alacritty", Title: "~: zsh"}, ""},
+		{xscan.Window{ApplicationName: "Alacritty", Title: home + ": zsh"}, ""},
+		{xscan.Window{ApplicationName: "Alacritty", Title: "htop"}, ""},
+		{xscan.Window{ApplicationName: "firefox", Title: "glider - Mozilla Firefox"}, ""},
+	} {
+		assert.Equal(t, testCase.project, c.Classify(testCase.window).Project, testCase.window.Title)
+	}
+}
@@ -0,0 +1,150 @@
+package local
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dwetterau/glider/local/calendar"
+	"github.com/dwetterau/glider/local/config"
+	"github.com/dwetterau/glider/local/thrash"
+	"github.com/dwetterau/glider/local/track"
+)
+
+const dateFormat = "2006-01-02"
+
+// Turns the report arguments into a range of whole local days [from, to).
+func ReportRange(now time.Time, day, fromRaw, toRaw string) (time.Time, time.Time, error) {
+	startOfDay := func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+	if fromRaw == "" {
+		if toRaw != "" {
+			return time.Time{}, time.Time{}, errors.New("-to can only be used with -from")
+		}
+		switch day {
+		case "", "today":
+			from := startOfDay(now)
+			return from, from.AddDate(0, 0, 1), nil
+		case "yesterday":
+			from := startOfDay(now).AddDate(0, 0, -1)
+			return from, from.AddDate(0, 0, 1), nil
+		default:
+			return time.Time{}, time.Time{}, fmt.Errorf("unknown day %q, use today, yesterday or -from", day)
+		}
+	}
+	if day != "" {
+		return time.Time{}, time.Time{}, errors.New("can't use both a day and -from")
+	}
+	from, err := time.ParseInLocation(dateFormat, fromRaw, now.Location())
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid -from date: %v", err)
+	}
+	to := from
+	if toRaw != "" {
+		to, err = time.ParseInLocation(dateFormat, toRaw, now.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid -to date: %v", err)
+		}
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, errors.New("-to is before -from")
+	}
+	return from, to.AddDate(0, 0, 1), nil
+}
+
+// Prints per-application and per-rule totals, the time spent on each project, the most
+// frequent switches and the focus sessions from the tracking log, and the meetings if
+// there were any. The config decides which time was productive.
+func WriteReport(
+	out io.Writer, tracker track.Tracker, c *config.Config, meetings []calendar.Occurrence, from, to time.Time,
+) error {
+	applications, err := tracker.ApplicationTotals(from, to)
+	if err != nil {
+		return err
+	}
+	rules, err := tracker.RuleTotals(from, to)
+	if err != nil {
+		return err
+	}
+	usage, err := tracker.Usage(from, to)
+	if err != nil {
+		return err
+	}
+	projects := newCategorizer(c.Categories, c.Rules).Summarize(usage).Projects
+	switches, err := tracker.Switches(from, to)
+	if err != nil {
+		return err
+	}
+	sessions, err := tracker.Sessions(from, to)
+	if err != nil {
+		return err
+	}
+
+	last := to.AddDate(0, 0, -1)
+	if last.Equal(from) {
+		fmt.Fprintf(out, "Report for %s\n", from.Format(dateFormat))
+	} else {
+		fmt.Fprintf(out, "Report for %s to %s\n", from.Format(dateFormat), last.Format(dateFormat))
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	for _, section := range []struct {
+		name   string
+		totals []track.Total
+	}{
+		{"Applications", applications},
+		{"Rules", rules},
+	} {
+		fmt.Fprintf(w, "\n%s:\n", section.name)
+		if len(section.totals) == 0 {
+			fmt.Fprintln(w, "  (nothing recorded)")
+		}
+		for _, total := range section.totals {
+			fmt.Fprintf(w, "  %s\t%v\n", total.Name, total.Duration.Round(time.Second))
+		}
+	}
+	if len(projects) > 0 {
+		fmt.Fprintln(w, "\nProjects:")
+		for _, p := range projects {
+			fmt.Fprintf(
+				w, "  %s\t%v\t%v productive\n",
+				p.Name, p.Total.Round(time.Second), p.Productive.Round(time.Second),
+			)
+		}
+	}
+	fmt.Fprintf(w, "\nSwitches (%d in total):\n", len(switches))
+	if len(switches) == 0 {
+		fmt.Fprintln(w, "  (nothing recorded)")
+	}
+	for _, pair := range thrash.TopPairs(switches, topSwitchPairs) {
+		fmt.Fprintf(w, "  %v\t%d\n", pair, pair.Count)
+	}
+	fmt.Fprintln(w, "\nFocus sessions:")
+	if len(sessions) == 0 {
+		fmt.Fprintln(w, "  (nothing recorded)")
+	}
+	for _, session := range sessions {
+		stopped := ""
+		if !session.Completed {
+			stopped = ", stopped early"
+		}
+		fmt.Fprintf(
+			w, "  %s\t%v\t%v focused, %v distracted, %v break%s\n",
+			session.Start.Format("Jan 2 "+time.Kitchen), session.Length,
+			session.Focused.Round(time.Second), session.Distracted.Round(time.Second),
+			session.Break.Round(time.Second), stopped,
+		)
+	}
+	if len(meetings) > 0 {
+		fmt.Fprintf(w, "\nMeetings (%v in total):\n", calendar.Total(meetings, from, to))
+		for _, meeting := range meetings {
+			fmt.Fprintf(
+				w, "  %s\t%v\t%s\n",
+				meeting.Start.Local().Format("Jan 2 "+time.Kitchen), meeting.End.Sub(meeting.Start), meeting.Event.Summary,
+			)
+		}
+	}
+	return w.Flush()
+}
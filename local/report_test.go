@@ -0,0 +1,45 @@
+package local
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportRange(t *testing.T) {
+	now := time.Date(2018, 9, 3, 15, 4, 5, 0, time.Local)
+	day := func(d int) time.Time {
+		return time.Date(2018, 9, d, 0, 0, 0, 0, time.Local)
+	}
+
+	for _, testCase := range []struct {
+		day, from, to            string
+		expectedFrom, expectedTo time.Time
+	}{
+		{"", "", "", day(3), day(4)},
+		{"today", "", "", day(3), day(4)},
+		{"yesterday", "", "", day(2), day(3)},
+		{"", "2018-09-01", "", day(1), day(2)},
+		{"", "2018-09-01", "2018-09-03", day(1), day(4)},
+	} {
+		from, to, err := ReportRange(now, testCase.day, testCase.from, testCase.to)
+		require.NoError(t, err)
+		assert.Equal(t, testCase.expectedFrom, from)
+		assert.Equal(t, testCase.expectedTo, to)
+	}
+
+	for _, testCase := range []struct {
+		day, from, to string
+	}{
+		{"tomorrow", "", ""},
+		{"today", "2018-09-01", ""},
+		{"", "", "2018-09-01"},
+		{"", "09/01/2018", ""},
+		{"", "2018-09-03", "2018-09-01"},
+	} {
+		_, _, err := ReportRange(now, testCase.day, testCase.from, testCase.to)
+		assert.Error(t, err, testCase)
+	}
+}
@@ -0,0 +1,65 @@
+package local
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dwetterau/glider/local/calendar"
+	"github.com/dwetterau/glider/local/config"
+	"github.com/dwetterau/glider/local/review"
+	"github.com/dwetterau/glider/local/track"
+)
+
+// Scores the recurring meetings in the calendars from the config that happen in
+// [from, to), see review.Review.
+func ReviewMeetings(c *config.Config, tracker track.Tracker, from, to time.Time) ([]review.Meeting, error) {
+	if len(c.Calendar.Paths) == 0 {
+		return nil, errors.New("there are no calendars to review, add paths to [calendar] in the config")
+	}
+	events, err := calendar.Load(c.Calendar.Paths)
+	if err != nil {
+		fmt.Printf("Some of the calendar couldn't be read: %v\n", err)
+	}
+	sessions, err := tracker.Sessions(from, to)
+	if err != nil {
+		return nil, err
+	}
+	// The time after meetings at the end of the range counts too.
+	samples, err := tracker.Samples(from, to.Add(review.After))
+	if err != nil {
+		return nil, err
+	}
+	return review.Review(events, sessions, samples, newCategorizer(c.Categories, c.Rules), from, to), nil
+}
+
+// Prints the meetings with what to do about them.
+func WriteMeetingReview(out io.Writer, meetings []review.Meeting) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	if len(meetings) == 0 {
+		fmt.Fprintln(w, "  (no recurring meetings)")
+	}
+	for _, m := range meetings {
+		suggestion := m.Suggestion
+		if m.Suggestion == review.Shorten {
+			suggestion = fmt.Sprintf("shorten to %v", m.ShortenTo)
+		}
+		details := []string{
+			fmt.Sprintf("%d × %v", m.Occurrences, m.Length),
+			fmt.Sprintf("%d attendees", m.Attendees),
+		}
+		if m.FocusOverlap > 0 {
+			details = append(details, fmt.Sprintf("%v during focus time", m.FocusOverlap))
+		}
+		if m.ProductiveAfter >= 0 {
+			details = append(details, fmt.Sprintf("%.0f%% productive in the %v after", 100*m.ProductiveAfter, review.After))
+		} else {
+			details = append(details, "nothing tracked after")
+		}
+		fmt.Fprintf(w, "  %d\t%s\t%s\t%s\n", m.Score, suggestion, m.Summary, strings.Join(details, ", "))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,179 @@
+package review
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/dwetterau/glider/local/calendar"
+	"github.com/dwetterau/glider/local/category"
+	"github.com/dwetterau/glider/local/track"
+)
+
+// What to do about a meeting.
+const (
+	Drop    = "drop"
+	Shorten = "shorten"
+	Keep    = "keep"
+)
+
+// How long after a meeting the tracking log is checked for productive time.
+const After = time.Hour
+
+// Meetings with this many attendees or more count as big as it gets.
+const crowded = 10
+
+// A recurring meeting and how much it gets in the way.
+type Meeting struct {
+	Summary     string
+	Occurrences int
+	// How long the meeting usually is.
+	Length    time.Duration
+	Total     time.Duration
+	Attendees int
+	// How much of the meeting's time overlaps focus sessions and blocked off time.
+	FocusOverlap time.Duration
+	// The share of the time After the meeting that was productive, or -1 if nothing was
+	// tracked after it.
+	ProductiveAfter float64
+
+	// From 0 to 100, higher means the meeting is more worth dropping. See Review.
+	Score      int
+	Suggestion string
+	// What to shorten the meeting to, if that's the suggestion.
+	ShortenTo time.Duration
+}
+
+// Scores every recurring meeting that happens in [from, to), most worth dropping first.
+//
+// The score averages three things: how many attendees there are (the more there are,
+// the less you'll be missed), how much of the meeting overlaps focus time (glider focus
+// sessions, or calendar events that block off time without being meetings), and how
+// little productive time followed it. Meetings that score 60 or more are worth dropping,
+// and ones that score 40 or more are worth cutting in half.
+func Review(
+	events []calendar.Event, sessions []track.Session, samples []track.Sample,
+	categorizer *category.Categorizer, from, to time.Time,
+) []Meeting {
+	occurrences := calendar.Occurrences(events, from, to)
+	var blocks []calendar.Occurrence
+	for _, session := range sessions {
+		blocks = append(blocks, calendar.Occurrence{Start: session.Start, End: session.Start.Add(session.Length)})
+	}
+	// Recurring meetings are grouped by their UID, overrides of single occurrences
+	// included.
+	byUID := make(map[string][]calendar.Occurrence)
+	var uids []string
+	for _, occurrence := range occurrences {
+		event := occurrence.Event
+		if focusBlock(*event) {
+			blocks = append(blocks, occurrence)
+		}
+		if !event.Meeting() || event.UID == "" || (event.Recurrence == nil && event.RecurrenceID.IsZero()) {
+			continue
+		}
+		if _, ok := byUID[event.UID]; !ok {
+			uids = append(uids, event.UID)
+		}
+		byUID[event.UID] = append(byUID[event.UID], occurrence)
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Start.Before(blocks[j].Start) })
+
+	var meetings []Meeting
+	for _, uid := range uids {
+		meetings = append(meetings, review(byUID[uid], blocks, samples, categorizer))
+	}
+	sort.SliceStable(meetings, func(i, j int) bool {
+		if meetings[i].Score != meetings[j].Score {
+			return meetings[i].Score > meetings[j].Score
+		}
+		return meetings[i].Total > meetings[j].Total
+	})
+	return meetings
+}
+
+// Blocked off time is anything that keeps you busy without being a meeting.
+func focusBlock(event calendar.Event) bool {
+	return !event.Meeting() && !event.AllDay && event.Duration > 0 && !event.Transparent && !event.Cancelled
+}
+
+func review(
+	occurrences []calendar.Occurrence, blocks []calendar.Occurrence, samples []track.Sample,
+	categorizer *category.Categorizer,
+) Meeting {
+	m := Meeting{Occurrences: len(occurrences), ProductiveAfter: -1}
+	var lengths []time.Duration
+	var productive, tracked time.Duration
+	for _, occurrence := range occurrences {
+		// Overrides can be renamed or moved, the rest of the series is what counts.
+		if occurrence.Event.RecurrenceID.IsZero() || m.Summary == "" {
+			m.Summary = occurrence.Event.Summary
+			m.Attendees = occurrence.Event.Attendees
+		}
+		length := occurrence.End.Sub(occurrence.Start)
+		lengths = append(lengths, length)
+		m.Total += length
+		m.FocusOverlap += calendar.Total(blocks, occurrence.Start, occurrence.End)
+		p, t := productiveTime(samples, categorizer, occurrence.End, occurrence.End.Add(After))
+		productive += p
+		tracked += t
+	}
+	sort.Slice(lengths, func(i, j int) bool { return lengths[i] < lengths[j] })
+	m.Length = lengths[len(lengths)/2]
+
+	scores := []float64{
+		math.Min(float64(m.Attendees), crowded) / crowded,
+		float64(m.FocusOverlap) / float64(m.Total),
+	}
+	if tracked > 0 {
+		m.ProductiveAfter = float64(productive) / float64(tracked)
+		scores = append(scores, 1-m.ProductiveAfter)
+	}
+	var sum float64
+	for _, score := range scores {
+		sum += score
+	}
+	m.Score = int(math.Round(100 * sum / float64(len(scores))))
+
+	switch {
+	case m.Score >= 60:
+		m.Suggestion = Drop
+	case m.Score >= 40 && m.Length > 15*time.Minute:
+		m.Suggestion = Shorten
+		m.ShortenTo = (m.Length / 2).Round(5 * time.Minute)
+		if m.ShortenTo < 15*time.Minute {
+			m.ShortenTo = 15 * time.Minute
+		}
+	default:
+		m.Suggestion = Keep
+	}
+	return m
+}
+
+// Returns how much of [from, to) was spent on productive windows, and how much of it was
+// tracked at all, idle time included. The samples are ordered by time.
+func productiveTime(
+	samples []track.Sample, categorizer *category.Categorizer, from, to time.Time,
+) (time.Duration, time.Duration) {
+	var productive, tracked time.Duration
+	i := sort.Search(len(samples), func(i int) bool {
+		return samples[i].Time.Add(samples[i].Duration).After(from)
+	})
+	for ; i < len(samples) && samples[i].Time.Before(to); i++ {
+		start, end := samples[i].Time, samples[i].Time.Add(samples[i].Duration)
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if !end.After(start) {
+			continue
+		}
+		tracked += end.Sub(start)
+		if categorizer.Categorize(samples[i].ApplicationName, samples[i].Rule) == category.Productive {
+			productive += end.Sub(start)
+		}
+	}
+	return productive, tracked
+}
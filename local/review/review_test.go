@@ -0,0 +1,89 @@
+package review
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dwetterau/glider/local/calendar"
+	"github.com/dwetterau/glider/local/category"
+	"github.com/dwetterau/glider/local/track"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReview(t *testing.T) {
+	attendees := func(n int) string {
+		return strings.Repeat("ATTENDEE:mailto:someone@example.com\n", n)
+	}
+	events, err := calendar.Parse(strings.NewReader(`BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:sync
+SUMMARY:Weekly sync
+DTSTART:20180903T100000Z
+DURATION:PT1H
+RRULE:FREQ=WEEKLY
+` + attendees(12) + `END:VEVENT
+BEGIN:VEVENT
+UID:focus
+SUMMARY:Focus time
+DTSTART:20180903T100000Z
+DURATION:PT2H
+END:VEVENT
+BEGIN:VEVENT
+UID:standup
+SUMMARY:Standup
+DTSTART:20180903T090000Z
+DURATION:PT30M
+RRULE:FREQ=DAILY;COUNT=5
+` + attendees(2) + `END:VEVENT
+BEGIN:VEVENT
+UID:design
+SUMMARY:Design review
+DTSTART:20180905T140000Z
+DURATION:PT1H
+RRULE:FREQ=WEEKLY
+` + attendees(5) + `END:VEVENT
+BEGIN:VEVENT
+UID:offsite
+SUMMARY:Offsite planning
+DTSTART:20180906T140000Z
+DURATION:PT3H
+` + attendees(20) + `END:VEVENT
+END:VCALENDAR
+`))
+	require.NoError(t, err)
+
+	from := time.Date(2018, 9, 3, 0, 0, 0, 0, time.UTC)
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2018, 9, day, hour, minute, 0, 0, time.UTC)
+	}
+	sessions := []track.Session{{Start: at(5, 14, 0), Length: 30 * time.Minute}}
+	var samples []track.Sample
+	for day := 3; day <= 7; day++ {
+		samples = append(samples, track.Sample{Time: at(day, 9, 30), Duration: time.Hour, ApplicationName: "Code"})
+		if day == 3 {
+			samples = append(samples, track.Sample{
+				Time: at(day, 11, 0), Duration: time.Hour, ApplicationName: "Slack", Rule: "slack",
+			})
+		}
+	}
+	categorizer := category.NewCategorizer(category.Config{}, map[string]string{"slack": ""})
+
+	meetings := Review(events, sessions, samples, categorizer, from, from.AddDate(0, 0, 7))
+	assert.Equal(t, []Meeting{
+		{
+			Summary: "Weekly sync", Occurrences: 1, Length: time.Hour, Total: time.Hour, Attendees: 12,
+			FocusOverlap: time.Hour, ProductiveAfter: 0, Score: 100, Suggestion: Drop,
+		},
+		{
+			Summary: "Design review", Occurrences: 1, Length: time.Hour, Total: time.Hour, Attendees: 5,
+			FocusOverlap: 30 * time.Minute, ProductiveAfter: -1, Score: 50, Suggestion: Shorten,
+			ShortenTo: 30 * time.Minute,
+		},
+		{
+			Summary: "Standup", Occurrences: 5, Length: 30 * time.Minute, Total: 150 * time.Minute, Attendees: 2,
+			ProductiveAfter: 1, Score: 7, Suggestion: Keep,
+		},
+	}, meetings)
+}
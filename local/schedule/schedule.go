@@ -0,0 +1,268 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// When something is active. Each expression is either an interval like
+// "Mon-Fri 09:00-12:00", or a cron expression like "* 9-11 * * 1-5" that is active during
+// every minute it matches. A schedule is active whenever any of its expressions is, and
+// an empty schedule is always active.
+type Schedule []Expression
+
+type Expression interface {
+	// Reports whether t falls inside the expression, in t's location.
+	Active(t time.Time) bool
+	String() string
+}
+
+// Parses each expression, see Schedule.
+func Parse(expressions []string) (Schedule, error) {
+	var s Schedule
+	for _, raw := range expressions {
+		e, err := ParseExpression(raw)
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, e)
+	}
+	return s, nil
+}
+
+func ParseExpression(raw string) (Expression, error) {
+	fields := strings.Fields(raw)
+	var e Expression
+	var err error
+	if len(fields) == 5 {
+		e, err = parseCron(raw, fields)
+	} else {
+		e, err = parseInterval(raw, fields)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("schedule %q: %v", raw, err)
+	}
+	return e, nil
+}
+
+func (s Schedule) Active(t time.Time) bool {
+	if len(s) == 0 {
+		return true
+	}
+	for _, e := range s {
+		if e.Active(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Schedule) Strings() []string {
+	strs := make([]string, len(s))
+	for i, e := range s {
+		strs[i] = e.String()
+	}
+	return strs
+}
+
+// Active between two times of day on some days of the week. An interval that ends before
+// it starts runs past midnight, into the day after each of its days.
+type interval struct {
+	raw        string
+	days       [7]bool
+	start, end int // Minutes since midnight.
+}
+
+var dayNames = []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+
+func parseInterval(raw string, fields []string) (interval, error) {
+	i := interval{raw: raw}
+	switch len(fields) {
+	case 1:
+		for day := range i.days {
+			i.days[day] = true
+		}
+	case 2:
+		if err := parseDays(fields[0], &i.days); err != nil {
+			return i, err
+		}
+		fields = fields[1:]
+	default:
+		return i, fmt.Errorf("expected days and a time range like Mon-Fri 09:00-12:00")
+	}
+	times := strings.Split(fields[0], "-")
+	if len(times) != 2 {
+		return i, fmt.Errorf("expected a time range like 09:00-12:00, got %q", fields[0])
+	}
+	var err error
+	if i.start, err = parseTimeOfDay(times[0]); err != nil {
+		return i, err
+	}
+	if i.end, err = parseTimeOfDay(times[1]); err != nil {
+		return i, err
+	}
+	if i.start == i.end {
+		return i, fmt.Errorf("the time range is empty")
+	}
+	return i, nil
+}
+
+// Parses comma separated day names and ranges of them like "Mon-Fri,Sun".
+func parseDays(raw string, days *[7]bool) error {
+	for _, part := range strings.Split(raw, ",") {
+		bounds := strings.Split(part, "-")
+		if len(bounds) > 2 {
+			return fmt.Errorf("invalid day range %q", part)
+		}
+		first, err := parseDay(bounds[0])
+		if err != nil {
+			return err
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = parseDay(bounds[1]); err != nil {
+				return err
+			}
+		}
+		// Ranges can wrap around the end of the week, e.g. Fri-Mon.
+		for day := first; ; day = (day + 1) % 7 {
+			days[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// Days can be abbreviated down to three letters.
+func parseDay(raw string) (int, error) {
+	name := strings.ToLower(raw)
+	for i, dayName := range dayNames {
+		if len(name) >= 3 && strings.HasPrefix(dayName, name) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown day %q", raw)
+}
+
+func parseTimeOfDay(raw string) (int, error) {
+	t, err := time.Parse("15:04", raw)
+	if err != nil {
+		if raw == "24:00" {
+			return 24 * 60, nil
+		}
+		return 0, fmt.Errorf("invalid time %q, use HH:MM", raw)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (i interval) Active(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	day := int(t.Weekday())
+	if i.start < i.end {
+		return i.days[day] && minute >= i.start && minute < i.end
+	}
+	yesterday := (day + 6) % 7
+	return (i.days[day] && minute >= i.start) || (i.days[yesterday] && minute < i.end)
+}
+
+func (i interval) String() string {
+	return i.raw
+}
+
+// The standard five cron fields: minute, hour, day of month, month and day of week.
+type cron struct {
+	raw                               string
+	minutes, hours, monthDays, months []bool
+	weekDays                          []bool
+	// Like cron, when both days are restricted either one matching is enough.
+	anyMonthDay, anyWeekDay bool
+}
+
+func parseCron(raw string, fields []string) (cron, error) {
+	c := cron{raw: raw}
+	var err error
+	if c.minutes, err = parseCronField(fields[0], 0, 59); err != nil {
+		return c, fmt.Errorf("minute: %v", err)
+	}
+	if c.hours, err = parseCronField(fields[1], 0, 23); err != nil {
+		return c, fmt.Errorf("hour: %v", err)
+	}
+	if c.monthDays, err = parseCronField(fields[2], 1, 31); err != nil {
+		return c, fmt.Errorf("day of month: %v", err)
+	}
+	if c.months, err = parseCronField(fields[3], 1, 12); err != nil {
+		return c, fmt.Errorf("month: %v", err)
+	}
+	if c.weekDays, err = parseCronField(fields[4], 0, 7); err != nil {
+		return c, fmt.Errorf("day of week: %v", err)
+	}
+	// Both 0 and 7 are Sunday.
+	c.weekDays[0] = c.weekDays[0] || c.weekDays[7]
+	c.anyMonthDay = strings.HasPrefix(fields[2], "*")
+	c.anyWeekDay = strings.HasPrefix(fields[4], "*")
+	return c, nil
+}
+
+// Parses comma separated values, ranges and steps like "*/15", "5/15" or "1-5,7". A step
+// after a single value goes from that value up to max.
+func parseCronField(raw string, min, max int) ([]bool, error) {
+	matches := make([]bool, max+1)
+	for _, part := range strings.Split(raw, ",") {
+		step, stepped := 1, false
+		if i := strings.Index(part, "/"); i != -1 {
+			stepped = true
+			var err error
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step in %q", part)
+			}
+			part = part[:i]
+		}
+		first, last := min, max
+		if part != "*" {
+			bounds := strings.Split(part, "-")
+			if len(bounds) > 2 {
+				return nil, fmt.Errorf("invalid range %q", part)
+			}
+			var err error
+			if first, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value %q", bounds[0])
+			}
+			last = first
+			if stepped {
+				last = max
+			}
+			if len(bounds) == 2 {
+				if last, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid value %q", bounds[1])
+				}
+			}
+		}
+		if first < min || last > max || first > last {
+			return nil, fmt.Errorf("%q is out of range, values go from %d to %d", part, min, max)
+		}
+		for value := first; value <= last; value += step {
+			matches[value] = true
+		}
+	}
+	return matches, nil
+}
+
+func (c cron) Active(t time.Time) bool {
+	if !c.minutes[t.Minute()] || !c.hours[t.Hour()] || !c.months[t.Month()] {
+		return false
+	}
+	monthDay, weekDay := c.monthDays[t.Day()], c.weekDays[t.Weekday()]
+	if !c.anyMonthDay && !c.anyWeekDay {
+		return monthDay || weekDay
+	}
+	return monthDay && weekDay
+}
+
+func (c cron) String() string {
+	return c.raw
+}
@@ -0,0 +1,96 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 2018-09-03 was a Monday.
+func at(day, hour, minute int) time.Time {
+	return time.Date(2018, time.September, day, hour, minute, 0, 0, time.Local)
+}
+
+func TestInterval(t *testing.T) {
+	s, err := Parse([]string{"Mon-Fri 09:00-12:00", "sat 22:00-02:00"})
+	require.NoError(t, err)
+	for _, testCase := range []struct {
+		time   time.Time
+		active bool
+	}{
+		{at(3, 8, 59), false},
+		{at(3, 9, 0), true},
+		{at(7, 11, 59), true},
+		{at(7, 12, 0), false},
+		{at(8, 10, 0), false},
+		{at(8, 21, 59), false},
+		{at(8, 23, 0), true},
+		{at(9, 1, 59), true},
+		{at(9, 2, 0), false},
+		{at(9, 23, 0), false},
+	} {
+		assert.Equal(t, testCase.active, s.Active(testCase.time), testCase.time.String())
+	}
+}
+
+func TestCron(t *testing.T) {
+	s, err := Parse([]string{"*/30 9-11 * * 1-5", "* 14 1 * 0"})
+	require.NoError(t, err)
+	for _, testCase := range []struct {
+		time   time.Time
+		active bool
+	}{
+		{at(3, 9, 0), true},
+		{at(3, 9, 15), false},
+		{at(3, 11, 30), true},
+		{at(3, 12, 0), false},
+		{at(8, 9, 0), false},
+		// Restricting both days matches either one, like cron.
+		{at(1, 14, 10), true},
+		{at(9, 14, 59), true},
+		{at(4, 14, 0), false},
+	} {
+		assert.Equal(t, testCase.active, s.Active(testCase.time), testCase.time.String())
+	}
+
+	// A step after a single value goes on until the end of the field.
+	s, err = Parse([]string{"5/15 13/4 * * *"})
+	require.NoError(t, err)
+	for _, testCase := range []struct {
+		time   time.Time
+		active bool
+	}{
+		{at(3, 13, 5), true},
+		{at(3, 17, 20), true},
+		{at(3, 21, 50), true},
+		{at(3, 13, 0), false},
+		{at(3, 13, 15), false},
+		{at(3, 14, 5), false},
+		{at(3, 9, 5), false},
+	} {
+		assert.Equal(t, testCase.active, s.Active(testCase.time), testCase.time.String())
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	assert.True(t, Schedule(nil).Active(at(3, 3, 0)))
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, raw := range []string{
+		"",
+		"Mon-Fri",
+		"Funday 09:00-10:00",
+		"Mon 9am-10am",
+		"10:00-10:00",
+		"60 * * * *",
+		"* * * * 1-9",
+		"*/0 * * * *",
+		"5-1 * * * *",
+	} {
+		_, err := ParseExpression(raw)
+		assert.Error(t, err, raw)
+	}
+}
@@ -0,0 +1,67 @@
+package state
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dwetterau/glider/local/annoy"
+)
+
+// What the local daemon remembers across restarts.
+type State struct {
+	SavedAt time.Time                    `json:"saved_at"`
+	Buckets map[string]time.Duration     `json:"buckets"`
+	Budgets map[string]annoy.BudgetUsage `json:"budgets,omitempty"`
+}
+
+// Returns $XDG_STATE_HOME/glider/state.json, falling back to ~/.local/state.
+func DefaultPath() string {
+	stateHome := os.Getenv("XDG_STATE_HOME")
+	if stateHome == "" {
+		stateHome = filepath.Join(os.Getenv("HOME"), ".local", "state")
+	}
+	return filepath.Join(stateHome, "glider", "state.json")
+}
+
+// Reads the state saved at path. A missing file is the same as an empty state.
+func Load(path string) (State, error) {
+	s := State{}
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	err = json.Unmarshal(contents, &s)
+	return s, err
+}
+
+// Writes the state to path. The file is replaced atomically so a crash mid-write can't
+// leave a truncated state behind.
+func Save(path string, s State) error {
+	contents, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(contents)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,47 @@
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dwetterau/glider/local/annoy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state_test_dir")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "glider", "state.json")
+
+	// Nothing has been saved yet.
+	s, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, State{}, s)
+
+	saved := State{
+		SavedAt: time.Date(2018, 9, 3, 12, 0, 0, 0, time.UTC),
+		Buckets: map[string]time.Duration{"slack": 2*time.Minute + 59*time.Second},
+		Budgets: map[string]annoy.BudgetUsage{
+			"email": {Day: time.Date(2018, 9, 3, 4, 0, 0, 0, time.UTC), Used: 20 * time.Minute, Warned: 1},
+		},
+	}
+	require.NoError(t, Save(path, saved))
+	s, err = Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, saved, s)
+
+	// Saving again replaces the old state and doesn't leave temporary files around.
+	saved.Buckets["slack"] = 0
+	require.NoError(t, Save(path, saved))
+	s, err = Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, saved, s)
+	files, err := ioutil.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+}
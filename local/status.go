@@ -0,0 +1,95 @@
+package local
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dwetterau/glider/local/control"
+	"github.com/dwetterau/glider/local/focus"
+)
+
+// Prints how full each rule's bucket is in the running daemon.
+func WriteStatus(out io.Writer, status *control.Status) error {
+	if status.Paused {
+		fmt.Fprintln(out, "Paused, run resume to start annoying again.")
+	}
+	if meeting := status.Meeting; meeting != nil {
+		rules := "rules are suspended"
+		if meeting.Profile != "" {
+			rules = fmt.Sprintf("using the %s rules", meeting.Profile)
+		}
+		fmt.Fprintf(out, "In %q until %s, %s.\n", meeting.Summary, meeting.Until.Format(time.Kitchen), rules)
+	}
+	if session := status.Focus; session != nil {
+		if session.Phase == focus.PhaseBreak {
+			fmt.Fprintf(out, "On a break until %s.\n", session.Until.Format(time.Kitchen))
+		} else {
+			fmt.Fprintf(
+				out, "Focusing until %s, %v focused and %v distracted so far.\n",
+				session.Until.Format(time.Kitchen), session.Focused.Round(time.Second),
+				session.Distracted.Round(time.Second),
+			)
+		}
+	}
+	if score := status.Score; score != nil {
+		fmt.Fprintf(
+			out, "Focus score today: %d (%v productive, %v neutral, %v distracting)\n",
+			score.Value, score.Productive.Round(time.Minute), score.Neutral.Round(time.Minute),
+			score.Distracting.Round(time.Minute),
+		)
+		if len(score.Distractions) > 0 {
+			parts := make([]string, len(score.Distractions))
+			for i, distraction := range score.Distractions {
+				parts[i] = fmt.Sprintf("%s %v", distraction.Application, distraction.Duration.Round(time.Minute))
+			}
+			fmt.Fprintf(out, "Most distracting: %s\n", strings.Join(parts, ", "))
+		}
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	for _, rule := range status.Rules {
+		var notes []string
+		if rule.Budget > 0 {
+			notes = append(notes, fmt.Sprintf(
+				"%v of %v used today", rule.BudgetUsed.Round(time.Second), rule.Budget,
+			))
+		}
+		if rule.OffSchedule {
+			notes = append(notes, "off schedule")
+		}
+		if rule.SnoozedUntil != nil {
+			notes = append(notes, "snoozed until "+rule.SnoozedUntil.Format(time.Kitchen))
+		}
+		bucket := "-"
+		if rule.BucketSize > 0 {
+			bucket = fmt.Sprintf("%v / %v", rule.Bucket.Round(time.Second), rule.BucketSize)
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", rule.Name, bucket, strings.Join(notes, ", "))
+		for _, escalation := range rule.Escalations {
+			action := ""
+			if escalation.Action != "" {
+				action = ", " + escalation.Action
+			}
+			fmt.Fprintf(
+				w, "    %s\tstep %d (%s%s)\t%s\n",
+				escalation.Time.Format(time.Kitchen), escalation.Step+1, escalation.Urgency, action,
+				escalation.WindowTitle,
+			)
+		}
+	}
+	switching := status.Switching
+	alert := "alerts are off"
+	if switching.Threshold > 0 {
+		alert = fmt.Sprintf("alerting above %g", switching.Threshold)
+	}
+	fmt.Fprintf(
+		w, "\nSwitched windows %d times in the last %v (%.1f per minute, %s)\n",
+		switching.Switches, switching.Window, switching.PerMinute, alert,
+	)
+	for _, pair := range switching.TopPairs {
+		fmt.Fprintf(w, "  %s ↔ %s\t%d\n", pair.A, pair.B, pair.Count)
+	}
+	return w.Flush()
+}
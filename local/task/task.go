@@ -0,0 +1,260 @@
+package task
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dwetterau/glider/local/schedule"
+)
+
+// The priority of tasks that don't have one, lower than every todo.txt priority.
+const NoPriority = 26
+
+// An open task.
+type Task struct {
+	Description string
+	// 0 is the highest, like todo.txt's (A) and Taskwarrior's H, up to NoPriority.
+	Priority int
+	// Zero if the task isn't due.
+	Due time.Time
+	// The task shouldn't be worked on before this, zero if it can be worked on now. From
+	// todo.txt's t: or Taskwarrior's wait and scheduled.
+	Start time.Time
+	// @contexts in todo.txt, tags in Taskwarrior.
+	Contexts []string
+	Projects []string
+	// Taskwarrior's urgency, which breaks ties between tasks of the same priority.
+	Urgency float64
+}
+
+func (t Task) String() string {
+	s := t.Description
+	if t.Priority < NoPriority {
+		s = fmt.Sprintf("(%c) %s", 'A'+t.Priority, s)
+	}
+	if !t.Due.IsZero() {
+		s += ", due " + t.Due.Format("Mon Jan 2")
+	}
+	return s
+}
+
+// Where tasks come from, and when they can be worked on.
+type Config struct {
+	// A todo.txt file, see https://github.com/todotxt/todo.txt.
+	TodoTxt string
+	// A file with the output of `task export`.
+	Taskwarrior string
+	// When tasks in each context can be worked on, e.g. work tasks during work hours.
+	// Tasks in contexts that aren't listed can be worked on any time.
+	Contexts map[string]schedule.Schedule
+}
+
+// Reads the open tasks from the files in the config, in the order they're listed.
+func Load(c Config) ([]Task, error) {
+	var tasks []Task
+	for _, source := range []struct {
+		path  string
+		parse func(io.Reader) ([]Task, error)
+	}{
+		{c.TodoTxt, ParseTodoTxt},
+		{c.Taskwarrior, ParseTaskwarrior},
+	} {
+		if source.path == "" {
+			continue
+		}
+		f, err := os.Open(source.path)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := source.parse(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", source.path, err)
+		}
+		tasks = append(tasks, parsed...)
+	}
+	return tasks, nil
+}
+
+// Reports whether the task can be worked on at t: it has started, and if any of its
+// contexts have a schedule, one of them is active.
+func (c Config) Fits(task Task, t time.Time) bool {
+	if t.Before(task.Start) {
+		return false
+	}
+	scheduled := false
+	for _, context := range task.Contexts {
+		if s, ok := c.Contexts[context]; ok {
+			if s.Active(t) {
+				return true
+			}
+			scheduled = true
+		}
+	}
+	return !scheduled
+}
+
+// Returns the task to work on at t: the one with the highest priority of those that fit
+// the time, then the one that's due first, then the most urgent one, then the first one.
+// Returns false if no task fits.
+func (c Config) Next(tasks []Task, t time.Time) (Task, bool) {
+	var fitting []Task
+	for _, task := range tasks {
+		if c.Fits(task, t) {
+			fitting = append(fitting, task)
+		}
+	}
+	if len(fitting) == 0 {
+		return Task{}, false
+	}
+	sort.SliceStable(fitting, func(i, j int) bool {
+		a, b := fitting[i], fitting[j]
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+		if !a.Due.Equal(b.Due) {
+			return !a.Due.IsZero() && (b.Due.IsZero() || a.Due.Before(b.Due))
+		}
+		return a.Urgency > b.Urgency
+	})
+	return fitting[0], true
+}
+
+var (
+	todoPriority = regexp.MustCompile(`^\(([A-Z])\) `)
+	todoDate     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2} `)
+)
+
+// Reads the open tasks of a todo.txt file. Completed tasks, the ones starting with "x ",
+// are skipped.
+func ParseTodoTxt(r io.Reader) ([]Task, error) {
+	var tasks []Task
+	scanner := bufio.NewScanner(r)
+	number := 0
+	for scanner.Scan() {
+		number++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "x ") {
+			continue
+		}
+		task := Task{Priority: NoPriority}
+		if match := todoPriority.FindStringSubmatch(line); match != nil {
+			task.Priority = int(match[1][0] - 'A')
+			line = line[len(match[0]):]
+		}
+		// The creation date doesn't matter.
+		line = todoDate.ReplaceAllString(line, "")
+		var words []string
+		for _, word := range strings.Fields(line) {
+			var err error
+			switch {
+			case strings.HasPrefix(word, "due:"):
+				task.Due, err = time.ParseInLocation("2006-01-02", word[len("due:"):], time.Local)
+			case strings.HasPrefix(word, "t:"):
+				task.Start, err = time.ParseInLocation("2006-01-02", word[len("t:"):], time.Local)
+			default:
+				if len(word) > 1 && word[0] == '@' {
+					task.Contexts = append(task.Contexts, word[1:])
+				} else if len(word) > 1 && word[0] == '+' {
+					task.Projects = append(task.Projects, word[1:])
+				}
+				words = append(words, word)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %q is not a valid date", number, word)
+			}
+		}
+		task.Description = strings.Join(words, " ")
+		tasks = append(tasks, task)
+	}
+	return tasks, scanner.Err()
+}
+
+type rawTaskwarrior struct {
+	Description string   `json:"description"`
+	Status      string   `json:"status"`
+	Priority    string   `json:"priority"`
+	Due         string   `json:"due"`
+	Wait        string   `json:"wait"`
+	Scheduled   string   `json:"scheduled"`
+	Project     string   `json:"project"`
+	Tags        []string `json:"tags"`
+	Urgency     float64  `json:"urgency"`
+}
+
+var taskwarriorPriorities = map[string]int{"H": 0, "M": 1, "L": 2}
+
+// Reads the open tasks from the output of `task export`, which is either a JSON array or,
+// from older versions, one JSON object per line.
+func ParseTaskwarrior(r io.Reader) ([]Task, error) {
+	contents, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var raws []rawTaskwarrior
+	if trimmed := bytes.TrimSpace(contents); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &raws)
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(contents))
+		for err == nil {
+			var raw rawTaskwarrior
+			if err = decoder.Decode(&raw); err == nil {
+				raws = append(raws, raw)
+			}
+		}
+		if err == io.EOF {
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	var tasks []Task
+	for _, raw := range raws {
+		if raw.Status != "pending" && raw.Status != "waiting" {
+			continue
+		}
+		task := Task{
+			Description: raw.Description,
+			Priority:    NoPriority,
+			Contexts:    raw.Tags,
+			Urgency:     raw.Urgency,
+		}
+		if priority, ok := taskwarriorPriorities[raw.Priority]; ok {
+			task.Priority = priority
+		}
+		if raw.Project != "" {
+			task.Projects = []string{raw.Project}
+		}
+		for _, field := range []struct {
+			value string
+			to    *time.Time
+		}{
+			{raw.Due, &task.Due},
+			{raw.Wait, &task.Start},
+			{raw.Scheduled, &task.Start},
+		} {
+			if field.value == "" {
+				continue
+			}
+			t, err := time.Parse("20060102T150405Z", field.value)
+			if err != nil {
+				return nil, fmt.Errorf("task %q: %q is not a valid time", raw.Description, field.value)
+			}
+			if t.After(*field.to) {
+				*field.to = t
+			}
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
@@ -0,0 +1,124 @@
+package task
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dwetterau/glider/local/schedule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 2018-09-03 was a Monday.
+func at(day, hour, minute int) time.Time {
+	return time.Date(2018, time.September, day, hour, minute, 0, 0, time.Local)
+}
+
+func TestParseTodoTxt(t *testing.T) {
+	tasks, err := ParseTodoTxt(strings.NewReader(`(A) 2018-09-01 Call mom @phone +family
+x 2018-09-02 2018-09-01 Done already
+
+Write the report +work @office due:2018-09-07
+(B) Renew passport t:2018-09-10
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []Task{
+		{Description: "Call mom @phone +family", Priority: 0, Contexts: []string{"phone"}, Projects: []string{"family"}},
+		{
+			Description: "Write the report +work @office", Priority: NoPriority, Due: at(7, 0, 0),
+			Contexts: []string{"office"}, Projects: []string{"work"},
+		},
+		{Description: "Renew passport", Priority: 1, Start: at(10, 0, 0)},
+	}, tasks)
+	assert.Equal(t, "(A) Call mom @phone +family", tasks[0].String())
+	assert.Equal(t, "Write the report +work @office, due Fri Sep 7", tasks[1].String())
+
+	_, err = ParseTodoTxt(strings.NewReader("Something\nSomething else due:tomorrow\n"))
+	assert.EqualError(t, err, `line 2: "due:tomorrow" is not a valid date`)
+}
+
+func TestParseTaskwarrior(t *testing.T) {
+	export := `{"description":"Fix the build","status":"pending","priority":"H","project":"glider","tags":["work"],"urgency":8.1}
+{"description":"Shipped","status":"completed","priority":"H"}
+{"description":"Taxes","status":"waiting","wait":"20180910T070000Z","due":"20180915T000000Z","urgency":2}
+`
+	expected := []Task{
+		{
+			Description: "Fix the build", Priority: 0, Contexts: []string{"work"}, Projects: []string{"glider"},
+			Urgency: 8.1,
+		},
+		{
+			Description: "Taxes", Priority: NoPriority, Due: time.Date(2018, 9, 15, 0, 0, 0, 0, time.UTC),
+			Start: time.Date(2018, 9, 10, 7, 0, 0, 0, time.UTC), Urgency: 2,
+		},
+	}
+	tasks, err := ParseTaskwarrior(strings.NewReader(export))
+	require.NoError(t, err)
+	assert.Equal(t, expected, tasks)
+
+	// Newer versions export an array.
+	array := "[\n" + strings.Replace(strings.TrimSpace(export), "}\n{", "},\n{", -1) + "\n]\n"
+	tasks, err = ParseTaskwarrior(strings.NewReader(array))
+	require.NoError(t, err)
+	assert.Equal(t, expected, tasks)
+
+	_, err = ParseTaskwarrior(strings.NewReader(`{"description":"Soon","status":"pending","due":"friday"}`))
+	assert.EqualError(t, err, `task "Soon": "friday" is not a valid time`)
+}
+
+func TestNext(t *testing.T) {
+	work, err := schedule.Parse([]string{"Mon-Fri 09:00-17:00"})
+	require.NoError(t, err)
+	c := Config{Contexts: map[string]schedule.Schedule{"work": work}}
+	tasks := []Task{
+		{Description: "Read a book", Priority: NoPriority},
+		{Description: "Fix the build", Priority: 0, Contexts: []string{"work"}},
+		{Description: "Write the report", Priority: 1, Contexts: []string{"work"}, Due: at(7, 0, 0)},
+		{Description: "Review the design", Priority: 1, Contexts: []string{"work"}, Due: at(5, 0, 0)},
+		{Description: "Call the bank", Priority: 1, Contexts: []string{"work", "phone"}, Urgency: 5},
+		{Description: "Renew passport", Priority: 0, Start: at(10, 0, 0)},
+	}
+	next := func(t time.Time) string {
+		task, ok := c.Next(tasks, t)
+		if !ok {
+			return ""
+		}
+		return task.Description
+	}
+
+	assert.Equal(t, "Fix the build", next(at(3, 10, 0)))
+	// Work tasks don't fit in the evening, even if they're in other contexts too.
+	assert.Equal(t, "Read a book", next(at(3, 20, 0)))
+	assert.Equal(t, "Renew passport", next(at(10, 20, 0)))
+
+	tasks = tasks[2:5]
+	assert.Equal(t, "Review the design", next(at(3, 10, 0)))
+	tasks = tasks[:2]
+	assert.Equal(t, "", next(at(3, 20, 0)))
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "task_test_dir")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	c := Config{TodoTxt: filepath.Join(dir, "todo.txt"), Taskwarrior: filepath.Join(dir, "export.json")}
+	require.NoError(t, ioutil.WriteFile(c.TodoTxt, []byte("(C) Water the plants\n"), 0600))
+	require.NoError(t, ioutil.WriteFile(c.Taskwarrior, []byte(`[{"description":"Fix the build","status":"pending"}]`), 0600))
+
+	tasks, err := Load(c)
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	assert.Equal(t, "Water the plants", tasks[0].Description)
+	assert.Equal(t, "Fix the build", tasks[1].Description)
+
+	require.NoError(t, ioutil.WriteFile(c.Taskwarrior, []byte("not json"), 0600))
+	_, err = Load(c)
+	assert.Error(t, err)
+
+	_, err = Load(Config{TodoTxt: filepath.Join(dir, "missing.txt")})
+	assert.Error(t, err)
+}
@@ -0,0 +1,124 @@
+package thrash
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Focus moving from one application to another.
+type Switch struct {
+	Time     time.Time
+	From, To string
+}
+
+// How many times focus moved between two applications, in either direction. A sorts
+// before B.
+type Pair struct {
+	A, B  string
+	Count int
+}
+
+func (p Pair) String() string {
+	return fmt.Sprintf("%s ↔ %s", p.A, p.B)
+}
+
+// Returns the n pairs with the most switches between them, most first.
+func TopPairs(switches []Switch, n int) []Pair {
+	counts := make(map[[2]string]int)
+	for _, s := range switches {
+		key := [2]string{s.From, s.To}
+		if key[1] < key[0] {
+			key[0], key[1] = key[1], key[0]
+		}
+		counts[key]++
+	}
+	pairs := make([]Pair, 0, len(counts))
+	for key, count := range counts {
+		pairs = append(pairs, Pair{A: key[0], B: key[1], Count: count})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Count != pairs[j].Count {
+			return pairs[i].Count > pairs[j].Count
+		}
+		if pairs[i].A != pairs[j].A {
+			return pairs[i].A < pairs[j].A
+		}
+		return pairs[i].B < pairs[j].B
+	})
+	if len(pairs) > n {
+		pairs = pairs[:n]
+	}
+	return pairs
+}
+
+type Config struct {
+	// Alert when focus switches more than this many times per minute, on average over
+	// Window. Zero turns alerts off.
+	Threshold float64
+	Window    time.Duration
+	// The least amount of time between two alerts.
+	Cooldown time.Duration
+	// The names of the notifiers to send alerts to. Defaults to the default notifier of
+	// the annoyer rules, or every notifier if that isn't configured.
+	Notifiers []string
+}
+
+func DefaultConfig() Config {
+	return Config{Threshold: 4, Window: 5 * time.Minute, Cooldown: 15 * time.Minute}
+}
+
+// Keeps track of the switches over a sliding window to notice when focus is bouncing
+// around too much.
+type Detector struct {
+	config    Config
+	switches  []Switch
+	lastAlert time.Time
+}
+
+func NewDetector(c Config) *Detector {
+	return &Detector{config: c}
+}
+
+func (d *Detector) SetConfig(c Config) {
+	d.config = c
+}
+
+func (d *Detector) Config() Config {
+	return d.config
+}
+
+// Records a switch and reports whether it's time to alert about the switch rate.
+func (d *Detector) Observe(s Switch) bool {
+	d.switches = append(d.switches, s)
+	d.forget(s.Time)
+	if d.config.Threshold <= 0 || d.Rate(s.Time) <= d.config.Threshold {
+		return false
+	}
+	if !d.lastAlert.IsZero() && s.Time.Sub(d.lastAlert) < d.config.Cooldown {
+		return false
+	}
+	d.lastAlert = s.Time
+	return true
+}
+
+// Returns the switches per minute over the window that ends at now.
+func (d *Detector) Rate(now time.Time) float64 {
+	return float64(len(d.Switches(now))) / d.config.Window.Minutes()
+}
+
+// Returns the switches in the window that ends at now, oldest first.
+func (d *Detector) Switches(now time.Time) []Switch {
+	d.forget(now)
+	return append([]Switch(nil), d.switches...)
+}
+
+// Drops the switches that slid out of the window.
+func (d *Detector) forget(now time.Time) {
+	start := now.Add(-d.config.Window)
+	i := 0
+	for i < len(d.switches) && !d.switches[i].Time.After(start) {
+		i++
+	}
+	d.switches = d.switches[i:]
+}
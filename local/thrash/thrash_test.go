@@ -0,0 +1,45 @@
+package thrash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetector(t *testing.T) {
+	d := NewDetector(Config{Threshold: 2, Window: 5 * time.Minute, Cooldown: 10 * time.Minute})
+	start := time.Date(2018, 9, 3, 9, 0, 0, 0, time.Local)
+	apps := []string{"Code", "Firefox", "Code", "Slack"}
+
+	// Ten switches in five minutes is exactly the threshold.
+	now := start
+	for i := 0; i < 10; i++ {
+		now = start.Add(time.Duration(i) * 30 * time.Second)
+		assert.False(t, d.Observe(Switch{Time: now, From: apps[i%4], To: apps[(i+1)%4]}), i)
+	}
+	assert.Equal(t, 2.0, d.Rate(now))
+	assert.True(t, d.Observe(Switch{Time: now.Add(time.Second), From: "Slack", To: "Code"}))
+	// Alerts don't repeat until the cooldown is over.
+	assert.False(t, d.Observe(Switch{Time: now.Add(2 * time.Second), From: "Code", To: "Slack"}))
+
+	// Old switches slide out of the window.
+	assert.Len(t, d.Switches(now.Add(5*time.Minute+time.Second)), 1)
+	assert.Empty(t, d.Switches(now.Add(time.Hour)))
+}
+
+func TestTopPairs(t *testing.T) {
+	switches := []Switch{
+		{From: "Code", To: "Firefox"},
+		{From: "Firefox", To: "Code"},
+		{From: "Code", To: "Slack"},
+		{From: "Slack", To: "Firefox"},
+		{From: "Firefox", To: "Slack"},
+		{From: "Code", To: "Firefox"},
+	}
+	assert.Equal(t, []Pair{
+		{A: "Code", B: "Firefox", Count: 3},
+		{A: "Firefox", B: "Slack", Count: 2},
+	}, TopPairs(switches, 2))
+	assert.Empty(t, TopPairs(nil, 3))
+}
@@ -1,9 +1,22 @@
 package tool
 
-import "os/exec"
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
 
 func Run(name string, args ...string) (string, error) {
 	cmd := exec.Command(name, args...)
 	stdout, err := cmd.CombinedOutput()
 	return string(stdout), err
 }
+
+// Replaces a leading ~ in path with $HOME.
+func ExpandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		return filepath.Join(os.Getenv("HOME"), path[1:])
+	}
+	return path
+}
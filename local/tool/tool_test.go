@@ -0,0 +1,22 @@
+package tool
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandHome(t *testing.T) {
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	os.Setenv("HOME", "/home/me")
+	for raw, expanded := range map[string]string{
+		"~":          "/home/me",
+		"~/todo.txt": "/home/me/todo.txt",
+		"/tmp/~":     "/tmp/~",
+		"~other/src": "~other/src",
+		"src":        "src",
+	} {
+		assert.Equal(t, expanded, ExpandHome(raw), raw)
+	}
+}
@@ -0,0 +1,186 @@
+package track
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/dwetterau/glider/local/thrash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func initTracker(t *testing.T) (Tracker, func()) {
+	name, err := ioutil.TempDir("", "sqlite_test_dir")
+	require.NoError(t, err)
+
+	tracker, err := NewSQLite(path.Join(name, "track.db"))
+	require.NoError(t, err)
+
+	return tracker, func() {
+		require.NoError(t, os.RemoveAll(name))
+	}
+}
+
+func TestTotals(t *testing.T) {
+	tracker, toDefer := initTracker(t)
+	defer toDefer()
+
+	start := time.Date(2018, 9, 3, 9, 0, 0, 0, time.Local)
+	samples := []Sample{
+		{Time: start, Duration: 5 * time.Second, ApplicationName: "Code", Title: "manager.go", Project: "glider"},
+		{Time: start.Add(5 * time.Second), Duration: 3 * time.Second, ApplicationName: "Slack", Title: "general", Rule: "slack"},
+		{Time: start.Add(8 * time.Second), Duration: 5 * time.Second, ApplicationName: "Slack", Title: "random", Rule: "slack"},
+		{Time: start.Add(13 * time.Second), Duration: 2 * time.Second, ApplicationName: "Code", Title: "wit.go", Project: "wit"},
+		// The next day
+		{Time: start.Add(24 * time.Hour), Duration: time.Minute, ApplicationName: "Firefox", Title: "Inbox - Gmail", Rule: "gmail"},
+	}
+	for _, sample := range samples {
+		require.NoError(t, tracker.Record(sample))
+	}
+
+	applications, err := tracker.ApplicationTotals(start, start.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []Total{
+		{Name: "Slack", Duration: 8 * time.Second},
+		{Name: "Code", Duration: 7 * time.Second},
+	}, applications)
+
+	// Windows that didn't match a rule aren't included in the rule totals.
+	rules, err := tracker.RuleTotals(start, start.Add(48*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []Total{
+		{Name: "gmail", Duration: time.Minute},
+		{Name: "slack", Duration: 8 * time.Second},
+	}, rules)
+
+	// The end of the range is exclusive.
+	rules, err = tracker.RuleTotals(start.Add(8*time.Second), start.Add(24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []Total{{Name: "slack", Duration: 5 * time.Second}}, rules)
+
+	recorded, err := tracker.Samples(start, start.Add(24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, samples[:4], recorded)
+
+	usage, err := tracker.Usage(start, start.Add(time.Hour))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []Usage{
+		{Application: "Code", Project: "glider", Duration: 5 * time.Second},
+		{Application: "Code", Project: "wit", Duration: 2 * time.Second},
+		{Application: "Slack", Rule: "slack", Duration: 8 * time.Second},
+	}, usage)
+}
+
+func TestSwitches(t *testing.T) {
+	tracker, toDefer := initTracker(t)
+	defer toDefer()
+
+	start := time.Date(2018, 9, 3, 9, 0, 0, 0, time.Local)
+	for i, application := range []string{"Code", "Code", "Firefox", "", "Code", "idle", "Slack", "Code"} {
+		require.NoError(t, tracker.Record(Sample{
+			Time: start.Add(time.Duration(i) * time.Minute), Duration: time.Minute, ApplicationName: application,
+		}))
+	}
+
+	switches, err := tracker.Switches(start, start.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []thrash.Switch{
+		{Time: start.Add(2 * time.Minute), From: "Code", To: "Firefox"},
+		{Time: start.Add(4 * time.Minute), From: "Firefox", To: "Code"},
+		{Time: start.Add(7 * time.Minute), From: "Slack", To: "Code"},
+	}, switches)
+}
+
+func TestSessions(t *testing.T) {
+	tracker, toDefer := initTracker(t)
+	defer toDefer()
+
+	start := time.Date(2018, 9, 3, 9, 0, 0, 0, time.Local)
+	sessions := []Session{
+		{
+			Start: start, End: start.Add(time.Hour), Length: 50 * time.Minute,
+			Focused: 45 * time.Minute, Distracted: 5 * time.Minute, Break: 10 * time.Minute, Completed: true,
+		},
+		{
+			Start: start.Add(2 * time.Hour), End: start.Add(2*time.Hour + 10*time.Minute), Length: 25 * time.Minute,
+			Focused: 10 * time.Minute,
+		},
+	}
+	for _, session := range sessions {
+		require.NoError(t, tracker.RecordSession(session))
+	}
+
+	recorded, err := tracker.Sessions(start, start.Add(24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, sessions, recorded)
+
+	recorded, err = tracker.Sessions(start.Add(time.Hour), start.Add(24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, sessions[1:], recorded)
+}
+
+func TestNags(t *testing.T) {
+	tracker, toDefer := initTracker(t)
+	defer toDefer()
+
+	start := time.Date(2018, 9, 3, 9, 0, 0, 0, time.Local)
+	nags := []Nag{
+		{Time: start, Rule: "slack"},
+		{Time: start.Add(time.Minute), Rule: "gmail"},
+		{Time: start.Add(time.Hour), Rule: "slack"},
+	}
+	for _, nag := range nags {
+		require.NoError(t, tracker.RecordNag(nag))
+	}
+
+	recorded, err := tracker.Nags(start, start.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, nags[:2], recorded)
+}
+
+func TestMigrate(t *testing.T) {
+	name, err := ioutil.TempDir("", "sqlite_test_dir")
+	require.NoError(t, err)
+	defer os.RemoveAll(name)
+	trackPath := path.Join(name, "track.db")
+
+	// Logs from before projects were tracked don't have the column.
+	database, err := sql.Open("sqlite3", trackPath)
+	require.NoError(t, err)
+	_, err = database.Exec(`CREATE TABLE samples (
+id INTEGER PRIMARY KEY,
+time INTEGER NOT NULL,
+duration INTEGER NOT NULL,
+application TEXT NOT NULL,
+title TEXT NOT NULL,
+rule TEXT NOT NULL
+)`)
+	require.NoError(t, err)
+	start := time.Date(2018, 9, 3, 9, 0, 0, 0, time.Local)
+	_, err = database.Exec(
+		"INSERT INTO samples (time, duration, application, title, rule) VALUES (?, ?, 'Code', 'manager.go', '')",
+		start.UnixNano(), time.Minute.Nanoseconds(),
+	)
+	require.NoError(t, err)
+	require.NoError(t, database.Close())
+
+	for i := 0; i < 2; i++ {
+		tracker, err := NewSQLite(trackPath)
+		require.NoError(t, err)
+		if i == 0 {
+			require.NoError(t, tracker.Record(Sample{
+				Time: start.Add(time.Minute), Duration: time.Minute, ApplicationName: "Code", Title: "wit.go", Project: "wit",
+			}))
+		}
+		samples, err := tracker.Samples(start, start.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, []Sample{
+			{Time: start, Duration: time.Minute, ApplicationName: "Code", Title: "manager.go"},
+			{Time: start.Add(time.Minute), Duration: time.Minute, ApplicationName: "Code", Title: "wit.go", Project: "wit"},
+		}, samples)
+	}
+}
@@ -0,0 +1,400 @@
+package track
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dwetterau/glider/local/thrash"
+	"github.com/dwetterau/glider/local/xscan"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// A stretch of time spent on a single window.
+type Sample struct {
+	Time            time.Time
+	Duration        time.Duration
+	ApplicationName string
+	Title           string
+	// The annoyer rule the window matched, if any.
+	Rule string
+	// The project the window was working on, if any. See the project package.
+	Project string
+}
+
+// How much time was spent on a single application or rule.
+type Total struct {
+	Name     string
+	Duration time.Duration
+}
+
+// How much time was spent on an application while it matched a rule, or no rule, and
+// worked on a project, or no project.
+type Usage struct {
+	Application string
+	Rule        string
+	Project     string
+	Duration    time.Duration
+}
+
+// A focus session, see the focus package.
+type Session struct {
+	Start time.Time
+	// When the session ended, including its break.
+	End time.Time
+	// How long the focus phase was supposed to last.
+	Length              time.Duration
+	Focused, Distracted time.Duration
+	Break               time.Duration
+	// Whether the focus phase ran its full length.
+	Completed bool
+}
+
+// A notification the annoyer sent about a rule.
+type Nag struct {
+	Time time.Time
+	Rule string
+}
+
+// Keeps a log of every sample the local daemon takes.
+type Tracker interface {
+	Record(sample Sample) error
+	// Returns the samples that started in [from, to), oldest first.
+	Samples(from, to time.Time) ([]Sample, error)
+
+	// Totals are for samples that started in [from, to), sorted by duration descending.
+	ApplicationTotals(from, to time.Time) ([]Total, error)
+	RuleTotals(from, to time.Time) ([]Total, error)
+	// Totals the samples in [from, to) by application, rule and project, in no
+	// particular order.
+	Usage(from, to time.Time) ([]Usage, error)
+
+	// Returns every time focus moved from one application to another in [from, to),
+	// oldest first. Going idle and coming back isn't a switch.
+	Switches(from, to time.Time) ([]thrash.Switch, error)
+
+	RecordSession(session Session) error
+	// Returns the sessions that started in [from, to), oldest first.
+	Sessions(from, to time.Time) ([]Session, error)
+
+	RecordNag(nag Nag) error
+	// Returns the nags sent in [from, to), oldest first.
+	Nags(from, to time.Time) ([]Nag, error)
+}
+
+// Returns $XDG_DATA_HOME/glider/track.db, falling back to ~/.local/share.
+func DefaultPath() string {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(os.Getenv("HOME"), ".local", "share")
+	}
+	return filepath.Join(dataHome, "glider", "track.db")
+}
+
+func NewSQLite(sourcePath string) (Tracker, error) {
+	err := os.MkdirAll(filepath.Dir(sourcePath), 0700)
+	if err != nil {
+		return nil, err
+	}
+	database, err := sql.Open("sqlite3", sourcePath)
+	if err != nil {
+		return nil, err
+	}
+	// Set up all the needed tables
+	for _, schema := range []string{
+		sampleTableCreateSchema,
+		sampleTableTimeIndexCreateSchema,
+		sessionTableCreateSchema,
+		nagTableCreateSchema,
+	} {
+		if _, err := database.Exec(schema); err != nil {
+			database.Close()
+			return nil, err
+		}
+	}
+	if err := migrate(database); err != nil {
+		database.Close()
+		return nil, err
+	}
+
+	return &trackerImpl{db: database}, nil
+}
+
+// Adds the columns that logs from older versions don't have yet.
+func migrate(database *sql.DB) error {
+	rows, err := database.Query("PRAGMA table_info(samples)")
+	if err != nil {
+		return err
+	}
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var id, notNull, primaryKey int
+		var name, columnType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&id, &name, &columnType, &notNull, &defaultValue, &primaryKey); err != nil {
+			rows.Close()
+			return err
+		}
+		columns[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if !columns["project"] {
+		if _, err := database.Exec("ALTER TABLE samples ADD COLUMN project TEXT NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const sampleTableCreateSchema = `
+CREATE TABLE IF NOT EXISTS samples (
+id INTEGER PRIMARY KEY,
+time INTEGER NOT NULL,
+duration INTEGER NOT NULL,
+application TEXT NOT NULL,
+title TEXT NOT NULL,
+rule TEXT NOT NULL,
+project TEXT NOT NULL DEFAULT ''
+)
+`
+
+const sampleTableTimeIndexCreateSchema = `
+CREATE INDEX IF NOT EXISTS time_idx ON samples (time)
+`
+
+const sessionTableCreateSchema = `
+CREATE TABLE IF NOT EXISTS sessions (
+id INTEGER PRIMARY KEY,
+start INTEGER NOT NULL,
+finish INTEGER NOT NULL,
+length INTEGER NOT NULL,
+focused INTEGER NOT NULL,
+distracted INTEGER NOT NULL,
+break_length INTEGER NOT NULL,
+completed INTEGER NOT NULL
+)
+`
+
+const nagTableCreateSchema = `
+CREATE TABLE IF NOT EXISTS nags (
+id INTEGER PRIMARY KEY,
+time INTEGER NOT NULL,
+rule TEXT NOT NULL
+)
+`
+
+type trackerImpl struct {
+	db *sql.DB
+}
+
+var _ Tracker = &trackerImpl{}
+
+func (t *trackerImpl) Record(sample Sample) error {
+	_, err := t.db.Exec(
+		"INSERT INTO samples "+
+			"(time, duration, application, title, rule, project) "+
+			"VALUES (?, ?, ?, ?, ?, ?)",
+		sample.Time.UnixNano(),
+		sample.Duration.Nanoseconds(),
+		sample.ApplicationName,
+		sample.Title,
+		sample.Rule,
+		sample.Project,
+	)
+	return err
+}
+
+func (t *trackerImpl) Samples(from, to time.Time) ([]Sample, error) {
+	rows, err := t.db.Query(
+		"SELECT time, duration, application, title, rule, project FROM samples "+
+			"WHERE time >= ? AND time < ? ORDER BY time ASC, id ASC",
+		from.UnixNano(), to.UnixNano(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	samples := make([]Sample, 0)
+	for rows.Next() {
+		var sample Sample
+		var timeRaw, durationRaw int64
+		err = rows.Scan(&timeRaw, &durationRaw, &sample.ApplicationName, &sample.Title, &sample.Rule, &sample.Project)
+		if err != nil {
+			return nil, err
+		}
+		sample.Time = time.Unix(0, timeRaw)
+		sample.Duration = time.Duration(durationRaw)
+		samples = append(samples, sample)
+	}
+	return samples, rows.Err()
+}
+
+func (t *trackerImpl) ApplicationTotals(from, to time.Time) ([]Total, error) {
+	return t.totals("application", from, to)
+}
+
+func (t *trackerImpl) RuleTotals(from, to time.Time) ([]Total, error) {
+	return t.totals("rule", from, to)
+}
+
+func (t *trackerImpl) Usage(from, to time.Time) ([]Usage, error) {
+	rows, err := t.db.Query(
+		"SELECT application, rule, project, SUM(duration) FROM samples "+
+			"WHERE time >= ? AND time < ? GROUP BY application, rule, project",
+		from.UnixNano(), to.UnixNano(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	usage := make([]Usage, 0)
+	for rows.Next() {
+		var u Usage
+		var durationRaw int64
+		err = rows.Scan(&u.Application, &u.Rule, &u.Project, &durationRaw)
+		if err != nil {
+			return nil, err
+		}
+		u.Duration = time.Duration(durationRaw)
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
+
+func (t *trackerImpl) totals(column string, from, to time.Time) ([]Total, error) {
+	rows, err := t.db.Query(
+		"SELECT "+column+", SUM(duration) AS total "+
+			"FROM samples WHERE time >= ? AND time < ? AND "+column+" != '' "+
+			"GROUP BY "+column+" ORDER BY total DESC, "+column+" ASC",
+		from.UnixNano(), to.UnixNano(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	totals := make([]Total, 0)
+	for rows.Next() {
+		var total Total
+		var durationRaw int64
+		err = rows.Scan(&total.Name, &durationRaw)
+		if err != nil {
+			return nil, err
+		}
+		total.Duration = time.Duration(durationRaw)
+		totals = append(totals, total)
+	}
+	return totals, rows.Err()
+}
+
+func (t *trackerImpl) Switches(from, to time.Time) ([]thrash.Switch, error) {
+	rows, err := t.db.Query(
+		"SELECT time, application FROM samples "+
+			"WHERE time >= ? AND time < ? ORDER BY time ASC, id ASC",
+		from.UnixNano(), to.UnixNano(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	switches := make([]thrash.Switch, 0)
+	last := ""
+	for rows.Next() {
+		var timeRaw int64
+		var application string
+		err = rows.Scan(&timeRaw, &application)
+		if err != nil {
+			return nil, err
+		}
+		switch application {
+		case "":
+			// Nothing was focused, which doesn't end the stretch on the last application.
+		case xscan.IdleWindow.ApplicationName:
+			last = ""
+		default:
+			if last != "" && application != last {
+				switches = append(switches, thrash.Switch{
+					Time: time.Unix(0, timeRaw), From: last, To: application,
+				})
+			}
+			last = application
+		}
+	}
+	return switches, rows.Err()
+}
+
+func (t *trackerImpl) RecordSession(session Session) error {
+	_, err := t.db.Exec(
+		"INSERT INTO sessions "+
+			"(start, finish, length, focused, distracted, break_length, completed) "+
+			"VALUES (?, ?, ?, ?, ?, ?, ?)",
+		session.Start.UnixNano(),
+		session.End.UnixNano(),
+		session.Length.Nanoseconds(),
+		session.Focused.Nanoseconds(),
+		session.Distracted.Nanoseconds(),
+		session.Break.Nanoseconds(),
+		session.Completed,
+	)
+	return err
+}
+
+func (t *trackerImpl) Sessions(from, to time.Time) ([]Session, error) {
+	rows, err := t.db.Query(
+		"SELECT start, finish, length, focused, distracted, break_length, completed "+
+			"FROM sessions WHERE start >= ? AND start < ? ORDER BY start ASC",
+		from.UnixNano(), to.UnixNano(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	sessions := make([]Session, 0)
+	for rows.Next() {
+		var session Session
+		var start, end, length, focused, distracted, breakLength int64
+		err = rows.Scan(&start, &end, &length, &focused, &distracted, &breakLength, &session.Completed)
+		if err != nil {
+			return nil, err
+		}
+		session.Start = time.Unix(0, start)
+		session.End = time.Unix(0, end)
+		session.Length = time.Duration(length)
+		session.Focused = time.Duration(focused)
+		session.Distracted = time.Duration(distracted)
+		session.Break = time.Duration(breakLength)
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (t *trackerImpl) RecordNag(nag Nag) error {
+	_, err := t.db.Exec("INSERT INTO nags (time, rule) VALUES (?, ?)", nag.Time.UnixNano(), nag.Rule)
+	return err
+}
+
+func (t *trackerImpl) Nags(from, to time.Time) ([]Nag, error) {
+	rows, err := t.db.Query(
+		"SELECT time, rule FROM nags WHERE time >= ? AND time < ? ORDER BY time ASC, id ASC",
+		from.UnixNano(), to.UnixNano(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	nags := make([]Nag, 0)
+	for rows.Next() {
+		var nag Nag
+		var timeRaw int64
+		err = rows.Scan(&timeRaw, &nag.Rule)
+		if err != nil {
+			return nil, err
+		}
+		nag.Time = time.Unix(0, timeRaw)
+		nags = append(nags, nag)
+	}
+	return nags, rows.Err()
+}
@@ -0,0 +1,51 @@
+package local
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dwetterau/glider/local/annoy"
+	"github.com/dwetterau/glider/local/track"
+	"github.com/dwetterau/glider/local/tune"
+)
+
+// Suggests new settings for the rules from the samples and nags in [from, to) of the
+// tracking log, see tune.Suggest.
+func Tune(tracker track.Tracker, rules []annoy.Rule, from, to time.Time) ([]tune.Suggestion, error) {
+	samples, err := tracker.Samples(from, to)
+	if err != nil {
+		return nil, err
+	}
+	nags, err := tracker.Nags(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return tune.Suggest(rules, samples, nags), nil
+}
+
+// Prints the suggestions next to the rules' current settings.
+func WriteTuning(out io.Writer, rules []annoy.Rule, suggestions []tune.Suggestion) error {
+	current := make(map[string]annoy.Rule, len(rules))
+	for _, rule := range rules {
+		current[rule.Name] = rule
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	if len(suggestions) == 0 {
+		fmt.Fprintln(w, "  (no rules with a bucket)")
+	}
+	for _, suggestion := range suggestions {
+		if suggestion.Skipped != "" {
+			fmt.Fprintf(w, "  %s\t%s\n", suggestion.Rule, suggestion.Skipped)
+			continue
+		}
+		rule := current[suggestion.Rule]
+		fmt.Fprintf(
+			w, "  %s\tbucket_size %v → %v\tdrain_factor %g → %g\t%d visits, %d of %d nags heeded\n",
+			suggestion.Rule, rule.BucketSize, suggestion.BucketSize, rule.DrainFactor, suggestion.DrainFactor,
+			suggestion.Visits, suggestion.Heeded, suggestion.Nags,
+		)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,157 @@
+package tune
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/dwetterau/glider/local/annoy"
+	"github.com/dwetterau/glider/local/track"
+)
+
+// Leaving a rule's windows this soon after a nag counts as heeding it.
+const HeedWindow = time.Minute
+
+// Rules with fewer visits than this in the history don't get a suggestion.
+const MinVisits = 5
+
+// Samples further apart than this, e.g. because glider wasn't running, are separate
+// visits even if they matched the same rule.
+const maxSampleGap = time.Minute
+
+// Time away from a rule's windows longer than this, like a night, says nothing about how
+// fast its bucket should drain.
+const maxAway = 4 * time.Hour
+
+// A stretch of consecutive samples that matched the same rule.
+type Visit struct {
+	Rule   string
+	Start  time.Time
+	Length time.Duration
+}
+
+func (v Visit) End() time.Time {
+	return v.Start.Add(v.Length)
+}
+
+// Splits the samples, oldest first, into visits to the rules they matched. Samples that
+// didn't match a rule, idle ones included, end the current visit.
+func Visits(samples []track.Sample) []Visit {
+	var visits []Visit
+	var current *Visit
+	for _, sample := range samples {
+		if current != nil && (sample.Rule != current.Rule || sample.Time.Sub(current.End()) > maxSampleGap) {
+			visits = append(visits, *current)
+			current = nil
+		}
+		if sample.Rule == "" {
+			continue
+		}
+		if current == nil {
+			current = &Visit{Rule: sample.Rule, Start: sample.Time}
+		}
+		current.Length = sample.Time.Add(sample.Duration).Sub(current.Start)
+	}
+	if current != nil {
+		visits = append(visits, *current)
+	}
+	return visits
+}
+
+// New settings for a rule, and what they're based on.
+type Suggestion struct {
+	Rule        string
+	BucketSize  time.Duration
+	DrainFactor float64
+
+	Visits int
+	// The nags sent about the rule, and how many of them were followed by leaving its
+	// windows within HeedWindow.
+	Nags, Heeded int
+
+	// Why there's no suggestion, empty if there is one.
+	Skipped string
+}
+
+// Suggests a bucket size and drain factor for every rule with a bucket.
+//
+// The bucket fits three out of four visits to the rule's windows, so only the long ones
+// get nagged about. If most nags were ignored, it fits nine out of ten instead: nags that
+// get ignored are more noise than help. The drain factor empties a typical visit's worth
+// of bucket in the typical time spent away from the rule's windows between visits.
+func Suggest(rules []annoy.Rule, samples []track.Sample, nags []track.Nag) []Suggestion {
+	visits := make(map[string][]Visit)
+	for _, visit := range Visits(samples) {
+		visits[visit.Rule] = append(visits[visit.Rule], visit)
+	}
+	var suggestions []Suggestion
+	for _, rule := range rules {
+		if rule.BucketSize <= 0 {
+			continue
+		}
+		suggestion := Suggestion{Rule: rule.Name, Visits: len(visits[rule.Name])}
+		for _, nag := range nags {
+			if nag.Rule != rule.Name {
+				continue
+			}
+			suggestion.Nags++
+			if heeded(visits[rule.Name], nag.Time) {
+				suggestion.Heeded++
+			}
+		}
+		if suggestion.Visits < MinVisits {
+			suggestion.Skipped = fmt.Sprintf("only %d visits, at least %d are needed", suggestion.Visits, MinVisits)
+			suggestions = append(suggestions, suggestion)
+			continue
+		}
+		lengths := make([]time.Duration, 0, len(visits[rule.Name]))
+		var away []time.Duration
+		for i, visit := range visits[rule.Name] {
+			lengths = append(lengths, visit.Length)
+			if i > 0 {
+				gap := visit.Start.Sub(visits[rule.Name][i-1].End())
+				if gap > 0 && gap <= maxAway {
+					away = append(away, gap)
+				}
+			}
+		}
+		share := .75
+		if suggestion.Nags >= 3 && suggestion.Heeded*2 < suggestion.Nags {
+			share = .9
+		}
+		suggestion.BucketSize = percentile(lengths, share).Round(time.Minute)
+		if suggestion.BucketSize < time.Minute {
+			suggestion.BucketSize = time.Minute
+		}
+		suggestion.DrainFactor = rule.DrainFactor
+		if len(away) > 0 {
+			factor := float64(percentile(lengths, .5)) / float64(percentile(away, .5))
+			suggestion.DrainFactor = math.Round(math.Min(math.Max(factor, .1), 10)*10) / 10
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions
+}
+
+// Reports whether the visit that was going on at the time of the nag ended within
+// HeedWindow. A nag outside of every visit was already heeded.
+func heeded(visits []Visit, at time.Time) bool {
+	for _, visit := range visits {
+		if !at.Before(visit.Start) && !at.After(visit.End()) {
+			return visit.End().Sub(at) < HeedWindow
+		}
+	}
+	return true
+}
+
+// Returns the duration that the given share of the durations are at most.
+func percentile(durations []time.Duration, share float64) time.Duration {
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	i := int(math.Ceil(share*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
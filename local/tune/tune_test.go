@@ -0,0 +1,71 @@
+package tune
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dwetterau/glider/local/annoy"
+	"github.com/dwetterau/glider/local/track"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVisits(t *testing.T) {
+	start := time.Date(2018, 9, 3, 9, 0, 0, 0, time.Local)
+	at := func(minutes int) time.Time {
+		return start.Add(time.Duration(minutes) * time.Minute)
+	}
+	samples := []track.Sample{
+		{Time: at(0), Duration: time.Minute, Rule: "slack"},
+		{Time: at(1), Duration: time.Minute, Rule: "slack"},
+		{Time: at(2), Duration: time.Minute, Rule: "gmail"},
+		{Time: at(3), Duration: time.Minute, ApplicationName: "idle"},
+		{Time: at(4), Duration: time.Minute, Rule: "gmail"},
+		// glider wasn't running for a while.
+		{Time: at(10), Duration: time.Minute, Rule: "gmail"},
+	}
+	assert.Equal(t, []Visit{
+		{Rule: "slack", Start: at(0), Length: 2 * time.Minute},
+		{Rule: "gmail", Start: at(2), Length: time.Minute},
+		{Rule: "gmail", Start: at(4), Length: time.Minute},
+		{Rule: "gmail", Start: at(10), Length: time.Minute},
+	}, Visits(samples))
+}
+
+func TestSuggest(t *testing.T) {
+	start := time.Date(2018, 9, 3, 9, 0, 0, 0, time.Local)
+	var samples []track.Sample
+	now := start
+	// Slack for 1, 2, 3, 4 and 10 minutes, with half an hour of work in between.
+	for _, minutes := range []int{1, 2, 3, 4, 10} {
+		length := time.Duration(minutes) * time.Minute
+		samples = append(samples,
+			track.Sample{Time: now, Duration: length, ApplicationName: "Slack", Rule: "slack"},
+			track.Sample{Time: now.Add(length), Duration: 30 * time.Minute, ApplicationName: "Code"},
+		)
+		now = now.Add(length + 30*time.Minute)
+	}
+	samples = append(samples, track.Sample{Time: now, Duration: time.Minute, Rule: "gmail"})
+	rules := []annoy.Rule{
+		{Name: "slack", BucketSize: 3 * time.Minute, DrainFactor: .5},
+		{Name: "gmail", BucketSize: 5 * time.Minute, DrainFactor: 10},
+		{Name: "email", Budget: annoy.Budget{Daily: time.Hour}},
+	}
+
+	suggestions := Suggest(rules, samples, nil)
+	assert.Equal(t, []Suggestion{
+		{Rule: "slack", BucketSize: 4 * time.Minute, DrainFactor: .1, Visits: 5},
+		{Rule: "gmail", Visits: 1, Skipped: "only 1 visits, at least 5 are needed"},
+	}, suggestions)
+
+	// The 4 minute visit ended right after its nag, the 10 minute one went on and on.
+	tenMinutes := samples[8].Time
+	nags := []track.Nag{
+		{Time: samples[6].Time.Add(3*time.Minute + 30*time.Second), Rule: "slack"},
+		{Time: tenMinutes.Add(3 * time.Minute), Rule: "slack"},
+		{Time: tenMinutes.Add(5 * time.Minute), Rule: "slack"},
+	}
+	suggestions = Suggest(rules[:1], samples, nags)
+	assert.Equal(t, []Suggestion{
+		{Rule: "slack", BucketSize: 10 * time.Minute, DrainFactor: .1, Visits: 5, Nags: 3, Heeded: 1},
+	}, suggestions)
+}
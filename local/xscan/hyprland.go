@@ -0,0 +1,82 @@
+package xscan
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Asks Hyprland which window is focused over its request socket.
+type hyprlandScanner struct {
+	socketPath string
+}
+
+// Connects to the Hyprland instance named by $HYPRLAND_INSTANCE_SIGNATURE.
+func NewHyprland() (Scanner, error) {
+	signature := os.Getenv("HYPRLAND_INSTANCE_SIGNATURE")
+	if signature == "" {
+		return nil, errors.New("HYPRLAND_INSTANCE_SIGNATURE is not set, is Hyprland running?")
+	}
+	// Newer versions of Hyprland keep their sockets in the runtime dir, older ones in /tmp.
+	candidates := []string{filepath.Join("/tmp", "hypr", signature, ".socket.sock")}
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		candidates = append([]string{filepath.Join(runtimeDir, "hypr", signature, ".socket.sock")}, candidates...)
+	}
+	for _, socketPath := range candidates {
+		if _, err := os.Stat(socketPath); err == nil {
+			return hyprlandScanner{socketPath: socketPath}, nil
+		}
+	}
+	return nil, fmt.Errorf("unable to find the Hyprland socket, tried %v", candidates)
+}
+
+type hyprlandWindow struct {
+	Address string `json:"address"`
+	Class   string `json:"class"`
+	Title   string `json:"title"`
+	PID     int    `json:"pid"`
+}
+
+func (s hyprlandScanner) CurrentWindow() (Window, error) {
+	conn, err := net.Dial("unix", s.socketPath)
+	if err != nil {
+		return Window{}, err
+	}
+	defer conn.Close()
+
+	// The "j/" prefix asks for JSON instead of the human readable output.
+	_, err = conn.Write([]byte("j/activewindow"))
+	if err != nil {
+		return Window{}, err
+	}
+	reply, err := ioutil.ReadAll(conn)
+	if err != nil {
+		return Window{}, err
+	}
+	var active hyprlandWindow
+	err = json.Unmarshal(bytes.TrimSpace(reply), &active)
+	if err != nil {
+		return Window{}, fmt.Errorf("unexpected activewindow reply from Hyprland %q: %v", reply, err)
+	}
+	if active.Address == "" {
+		// Nothing has focus.
+		return Window{}, nil
+	}
+	pid := ""
+	if active.PID > 0 {
+		pid = strconv.Itoa(active.PID)
+	}
+	return Window{
+		ApplicationName: active.Class,
+		Title:           active.Title,
+		PID:             pid,
+		WindowID:        active.Address,
+		Backend:         BackendHyprland,
+	}, nil
+}
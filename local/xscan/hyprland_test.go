@@ -0,0 +1,68 @@
+package xscan
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fakeHyprland(t *testing.T, reply string) (string, func()) {
+	return fakeSocket(t, func(conn net.Conn) {
+		request := make([]byte, 64)
+		n, err := conn.Read(request)
+		require.NoError(t, err)
+		assert.Equal(t, "j/activewindow", string(request[:n]))
+		_, err = conn.Write([]byte(reply))
+		require.NoError(t, err)
+	})
+}
+
+func TestHyprlandCurrentWindow(t *testing.T) {
+	socketPath, cleanup := fakeHyprland(t, `{
+		"address": "0x55d3c2a0", "mapped": true, "workspace": {"id": 1, "name": "1"},
+		"class": "firefox", "title": "Inbox - someone@example.com - Gmail — Mozilla Firefox",
+		"pid": 4242
+	}`)
+	defer cleanup()
+
+	window, err := hyprlandScanner{socketPath: socketPath}.CurrentWindow()
+	require.NoError(t, err)
+	assert.Equal(t, Window{
+		ApplicationName: "firefox",
+		Title:           "Inbox - someone@example.com - Gmail — Mozilla Firefox",
+		PID:             "4242",
+		WindowID:        "0x55d3c2a0",
+		Backend:         BackendHyprland,
+	}, window)
+}
+
+func TestHyprlandNothingFocused(t *testing.T) {
+	socketPath, cleanup := fakeHyprland(t, "{}")
+	defer cleanup()
+
+	window, err := hyprlandScanner{socketPath: socketPath}.CurrentWindow()
+	require.NoError(t, err)
+	assert.Equal(t, Window{}, window)
+}
+
+func TestNewHyprlandFindsSocket(t *testing.T) {
+	socketPath, cleanup := fakeHyprland(t, "{}")
+	defer cleanup()
+
+	// Lay the socket out the way Hyprland does under the runtime dir.
+	runtimeDir := filepath.Dir(socketPath)
+	require.NoError(t, os.MkdirAll(filepath.Join(runtimeDir, "hypr", "sig"), 0700))
+	require.NoError(t, os.Symlink(socketPath, filepath.Join(runtimeDir, "hypr", "sig", ".socket.sock")))
+	defer setenv(t, "XDG_RUNTIME_DIR", runtimeDir)()
+	defer setenv(t, "HYPRLAND_INSTANCE_SIGNATURE", "sig")()
+
+	scanner, err := NewHyprland()
+	require.NoError(t, err)
+	window, err := scanner.CurrentWindow()
+	require.NoError(t, err)
+	assert.Equal(t, Window{}, window)
+}
@@ -0,0 +1,151 @@
+package xscan
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dwetterau/glider/local/clock"
+)
+
+// A window a scanner saw, or the error it ran into, and when. Recordings are files of
+// samples, one JSON object per line.
+type Sample struct {
+	Time   time.Time `json:"time"`
+	Window Window    `json:"window"`
+	// Set instead of the window if the scanner failed.
+	Error string `json:"error,omitempty"`
+}
+
+// Returns a scanner that writes every window the given scanner finds to out, stamped
+// with the clock's time. Focus events are recorded too if the scanner pushes them.
+func NewRecorder(scanner Scanner, out io.Writer, clock clock.Clock) Scanner {
+	r := &recorder{scanner: scanner, encoder: json.NewEncoder(out), clock: clock}
+	if subscriber, ok := scanner.(Subscriber); ok {
+		return &subscribingRecorder{recorder: r, subscriber: subscriber}
+	}
+	return r
+}
+
+type recorder struct {
+	scanner Scanner
+	clock   clock.Clock
+	// Focus events are recorded from another goroutine.
+	lock    sync.Mutex
+	encoder *json.Encoder
+}
+
+func (r *recorder) CurrentWindow() (Window, error) {
+	window, err := r.scanner.CurrentWindow()
+	sample := Sample{Time: r.clock.Now(), Window: window}
+	if err != nil {
+		sample = Sample{Time: sample.Time, Error: err.Error()}
+	}
+	r.record(sample)
+	return window, err
+}
+
+func (r *recorder) record(sample Sample) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if err := r.encoder.Encode(sample); err != nil {
+		fmt.Printf("Unable to record a window: %v\n", err)
+	}
+}
+
+type subscribingRecorder struct {
+	*recorder
+	subscriber Subscriber
+}
+
+func (r *subscribingRecorder) Subscribe(done <-chan struct{}) (<-chan FocusEvent, error) {
+	events, err := r.subscriber.Subscribe(done)
+	if err != nil {
+		return nil, err
+	}
+	recorded := make(chan FocusEvent)
+	go func() {
+		defer close(recorded)
+		for event := range events {
+			r.record(Sample{Time: event.Time, Window: event.Window})
+			select {
+			case recorded <- event:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return recorded, nil
+}
+
+// Reads a recording made by NewRecorder, ordered by time.
+func ReadRecording(in io.Reader) ([]Sample, error) {
+	var samples []Sample
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(nil, 1024*1024)
+	number := 0
+	for scanner.Scan() {
+		number++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var sample Sample
+		if err := json.Unmarshal(scanner.Bytes(), &sample); err != nil {
+			return nil, fmt.Errorf("line %d: %v", number, err)
+		}
+		samples = append(samples, sample)
+	}
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+	return samples, scanner.Err()
+}
+
+// A scanner that plays back a recording by a fake clock, so that a day's worth of
+// windows can go by in no time. The focused window is the one from the last sample at
+// or before the clock's time.
+type Replayer struct {
+	samples []Sample
+	clock   *clock.Fake
+	// The sample the clock is at.
+	current int
+}
+
+var ErrNotStarted = errors.New("the recording hasn't started yet")
+
+// Sets the clock to the time of the first sample.
+func NewReplayer(samples []Sample, clock *clock.Fake) *Replayer {
+	if len(samples) > 0 {
+		clock.Set(samples[0].Time)
+	}
+	return &Replayer{samples: samples, clock: clock}
+}
+
+func (r *Replayer) CurrentWindow() (Window, error) {
+	now := r.clock.Now()
+	i := sort.Search(len(r.samples), func(i int) bool { return r.samples[i].Time.After(now) }) - 1
+	if i < 0 {
+		return Window{}, ErrNotStarted
+	}
+	if r.samples[i].Error != "" {
+		return Window{}, errors.New(r.samples[i].Error)
+	}
+	return r.samples[i].Window, nil
+}
+
+// Moves the clock to the next sample and returns how much time that skipped. Reports
+// false once the recording is over.
+func (r *Replayer) Step() (time.Duration, bool) {
+	now := r.clock.Now()
+	for r.current < len(r.samples) && !r.samples[r.current].Time.After(now) {
+		r.current++
+	}
+	if r.current == len(r.samples) {
+		return 0, false
+	}
+	r.clock.Set(r.samples[r.current].Time)
+	return r.samples[r.current].Time.Sub(now), true
+}
@@ -0,0 +1,109 @@
+package xscan
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dwetterau/glider/local/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingScanner struct{}
+
+func (failingScanner) CurrentWindow() (Window, error) {
+	return Window{}, errors.New("no display")
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	editor := Window{ApplicationName: "Code", Title: "manager.go", PID: "42"}
+	slack := Window{ApplicationName: "Slack", Title: "general | Team Slack"}
+	start := time.Date(2018, 9, 3, 9, 0, 0, 0, time.UTC)
+	c := clock.NewFake(start)
+	var out bytes.Buffer
+
+	recorder := NewRecorder(&fakeScanner{windows: []Window{editor, slack, editor}}, &out, c)
+	for i := 0; i < 3; i++ {
+		_, err := recorder.CurrentWindow()
+		require.NoError(t, err)
+		c.Advance(5 * time.Second)
+	}
+	_, err := NewRecorder(failingScanner{}, &out, c).CurrentWindow()
+	assert.EqualError(t, err, "no display")
+	assert.Equal(
+		t,
+		`{"time":"2018-09-03T09:00:00Z","window":{"application":"Code","title":"manager.go","pid":"42"}}`,
+		strings.SplitN(out.String(), "\n", 2)[0],
+	)
+
+	samples, err := ReadRecording(&out)
+	require.NoError(t, err)
+	require.Len(t, samples, 4)
+
+	replayed := clock.NewFake(time.Time{})
+	replayer := NewReplayer(samples, replayed)
+	assert.Equal(t, start, replayed.Now())
+	var windows []Window
+	var elapsed time.Duration
+	for {
+		window, err := replayer.CurrentWindow()
+		if err != nil {
+			assert.EqualError(t, err, "no display")
+		} else {
+			windows = append(windows, window)
+		}
+		step, ok := replayer.Step()
+		if !ok {
+			break
+		}
+		elapsed += step
+	}
+	assert.Equal(t, []Window{editor, slack, editor}, windows)
+	assert.Equal(t, 15*time.Second, elapsed)
+
+	// In between samples the last one is still focused.
+	replayed.Set(start.Add(7 * time.Second))
+	window, err := replayer.CurrentWindow()
+	require.NoError(t, err)
+	assert.Equal(t, slack, window)
+	replayed.Set(start.Add(-time.Second))
+	_, err = replayer.CurrentWindow()
+	assert.Equal(t, ErrNotStarted, err)
+
+	_, err = ReadRecording(strings.NewReader("{\"time\":\"2018-09-03T09:00:00Z\"}\nnot json\n"))
+	assert.EqualError(t, err, "line 2: invalid character 'o' in literal null (expecting 'u')")
+}
+
+type fakeSubscriber struct {
+	fakeScanner
+	events chan FocusEvent
+}
+
+func (f *fakeSubscriber) Subscribe(done <-chan struct{}) (<-chan FocusEvent, error) {
+	return f.events, nil
+}
+
+func TestRecordSubscriber(t *testing.T) {
+	editor := Window{ApplicationName: "Code", Title: "manager.go"}
+	subscriber := &fakeSubscriber{events: make(chan FocusEvent, 1)}
+	var out bytes.Buffer
+	recorder := NewRecorder(subscriber, &out, clock.Real{})
+
+	done := make(chan struct{})
+	defer close(done)
+	events, err := Watch(recorder, time.Hour, done)
+	require.NoError(t, err)
+	at := time.Date(2018, 9, 3, 9, 0, 0, 0, time.UTC)
+	subscriber.events <- FocusEvent{Window: editor, Time: at}
+	assert.Equal(t, FocusEvent{Window: editor, Time: at}, <-events)
+	close(subscriber.events)
+	_, ok := <-events
+	assert.False(t, ok)
+
+	samples, err := ReadRecording(&out)
+	require.NoError(t, err)
+	assert.Equal(t, []Sample{{Time: at, Window: editor}}, samples)
+}
@@ -0,0 +1,160 @@
+package xscan
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+)
+
+// Asks sway which window is focused over its i3 compatible IPC socket.
+type swayScanner struct {
+	socketPath string
+}
+
+// Connects to the sway instance named by $SWAYSOCK.
+func NewSway() (Scanner, error) {
+	socketPath := os.Getenv("SWAYSOCK")
+	if socketPath == "" {
+		return nil, errors.New("SWAYSOCK is not set, is sway running?")
+	}
+	return swayScanner{socketPath: socketPath}, nil
+}
+
+// Every i3 IPC message starts with this magic string, then the payload length and the
+// message type as native endian uint32s.
+const (
+	i3IPCMagic        = "i3-ipc"
+	i3IPCHeaderLength = len(i3IPCMagic) + 8
+	i3IPCRunCommand   = 0
+	i3IPCGetTree      = 4
+)
+
+type swayNode struct {
+	ID               int64  `json:"id"`
+	Name             string `json:"name"`
+	Type             string `json:"type"`
+	Focused          bool   `json:"focused"`
+	PID              int    `json:"pid"`
+	AppID            string `json:"app_id"`
+	WindowProperties struct {
+		Class string `json:"class"`
+	} `json:"window_properties"`
+	Nodes         []swayNode `json:"nodes"`
+	FloatingNodes []swayNode `json:"floating_nodes"`
+}
+
+func (s swayScanner) CurrentWindow() (Window, error) {
+	payload, err := i3IPCRequest(s.socketPath, i3IPCGetTree, nil)
+	if err != nil {
+		return Window{}, err
+	}
+	var root swayNode
+	err = json.Unmarshal(payload, &root)
+	if err != nil {
+		return Window{}, fmt.Errorf("unexpected get_tree reply from sway: %v", err)
+	}
+	focused := findFocusedSwayNode(&root)
+	if focused == nil || (focused.Type != "con" && focused.Type != "floating_con") {
+		// Nothing has focus, or an empty workspace does.
+		return Window{}, nil
+	}
+	// Native wayland windows have an app_id, xwayland ones only have the X11 class.
+	name := focused.AppID
+	if name == "" {
+		name = focused.WindowProperties.Class
+	}
+	pid := ""
+	if focused.PID != 0 {
+		pid = strconv.Itoa(focused.PID)
+	}
+	return Window{
+		ApplicationName: name,
+		Title:           focused.Name,
+		PID:             pid,
+		WindowID:        strconv.FormatInt(focused.ID, 10),
+		Backend:         BackendSway,
+	}, nil
+}
+
+// Runs a sway command, e.g. "[con_id=42] kill", on the sway instance named by $SWAYSOCK.
+func SwayCommand(command string) error {
+	socketPath := os.Getenv("SWAYSOCK")
+	if socketPath == "" {
+		return errors.New("SWAYSOCK is not set, is sway running?")
+	}
+	return swayCommand(socketPath, command)
+}
+
+func swayCommand(socketPath, command string) error {
+	payload, err := i3IPCRequest(socketPath, i3IPCRunCommand, []byte(command))
+	if err != nil {
+		return err
+	}
+	var results []struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+	}
+	err = json.Unmarshal(payload, &results)
+	if err != nil {
+		return fmt.Errorf("unexpected run_command reply from sway: %v", err)
+	}
+	for _, result := range results {
+		if !result.Success {
+			return fmt.Errorf("sway couldn't run %q: %s", command, result.Error)
+		}
+	}
+	return nil
+}
+
+func findFocusedSwayNode(node *swayNode) *swayNode {
+	if node.Focused {
+		return node
+	}
+	for _, children := range [][]swayNode{node.Nodes, node.FloatingNodes} {
+		for i := range children {
+			if focused := findFocusedSwayNode(&children[i]); focused != nil {
+				return focused
+			}
+		}
+	}
+	return nil
+}
+
+func i3IPCRequest(socketPath string, messageType uint32, payload []byte) ([]byte, error) {
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	header := make([]byte, i3IPCHeaderLength)
+	copy(header, i3IPCMagic)
+	binary.LittleEndian.PutUint32(header[len(i3IPCMagic):], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(header[len(i3IPCMagic)+4:], messageType)
+	_, err = conn.Write(append(header, payload...))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = io.ReadFull(conn, header)
+	if err != nil {
+		return nil, err
+	}
+	if string(header[:len(i3IPCMagic)]) != i3IPCMagic {
+		return nil, fmt.Errorf("unexpected reply header %q", header)
+	}
+	if replyType := binary.LittleEndian.Uint32(header[len(i3IPCMagic)+4:]); replyType != messageType {
+		return nil, fmt.Errorf("expected a reply of type %d, got %d", messageType, replyType)
+	}
+	reply := make([]byte, binary.LittleEndian.Uint32(header[len(i3IPCMagic):]))
+	_, err = io.ReadFull(conn, reply)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
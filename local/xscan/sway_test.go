@@ -0,0 +1,126 @@
+package xscan
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Listens on a unix socket in a temporary directory and answers every connection with
+// handler. Returns the socket path and a cleanup function.
+func fakeSocket(t *testing.T, handler func(conn net.Conn)) (string, func()) {
+	dir, err := ioutil.TempDir("", "xscan_socket")
+	require.NoError(t, err)
+	socketPath := filepath.Join(dir, "ipc.sock")
+	listener, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			handler(conn)
+			conn.Close()
+		}
+	}()
+	return socketPath, func() {
+		listener.Close()
+		require.NoError(t, os.RemoveAll(dir))
+	}
+}
+
+func fakeSway(t *testing.T, tree string) (string, func()) {
+	return fakeSocket(t, func(conn net.Conn) {
+		header := make([]byte, i3IPCHeaderLength)
+		_, err := io.ReadFull(conn, header)
+		require.NoError(t, err)
+		assert.Equal(t, i3IPCMagic, string(header[:len(i3IPCMagic)]))
+		assert.Equal(t, uint32(i3IPCGetTree), binary.LittleEndian.Uint32(header[len(i3IPCMagic)+4:]))
+
+		binary.LittleEndian.PutUint32(header[len(i3IPCMagic):], uint32(len(tree)))
+		_, err = conn.Write(append(header, tree...))
+		require.NoError(t, err)
+	})
+}
+
+const swayTree = `{
+  "id": 1, "type": "root", "name": "root", "focused": false,
+  "nodes": [{
+    "id": 2, "type": "output", "name": "eDP-1", "focused": false,
+    "nodes": [{
+      "id": 3, "type": "workspace", "name": "1", "focused": false,
+      "nodes": [
+        {"id": 4, "type": "con", "name": "manager.go - glider", "focused": false, "pid": 10, "app_id": "code"}
+      ],
+      "floating_nodes": [
+        {"id": 5, "type": "floating_con", "name": "general | Team Slack", "focused": %s, "pid": 11,
+         "app_id": null, "window_properties": {"class": "Slack", "instance": "slack"}}
+      ]
+    }]
+  }]
+}`
+
+func TestSwayCurrentWindow(t *testing.T) {
+	socketPath, cleanup := fakeSway(t, fmt.Sprintf(swayTree, "true"))
+	defer cleanup()
+
+	window, err := swayScanner{socketPath: socketPath}.CurrentWindow()
+	require.NoError(t, err)
+	assert.Equal(t, Window{
+		ApplicationName: "Slack",
+		Title:           "general | Team Slack",
+		PID:             "11",
+		WindowID:        "5",
+		Backend:         BackendSway,
+	}, window)
+}
+
+func TestSwayNothingFocused(t *testing.T) {
+	socketPath, cleanup := fakeSway(t, fmt.Sprintf(swayTree, "false"))
+	defer cleanup()
+
+	window, err := swayScanner{socketPath: socketPath}.CurrentWindow()
+	require.NoError(t, err)
+	assert.Equal(t, Window{}, window)
+}
+
+func TestSwayCommand(t *testing.T) {
+	commands := make(chan string, 2)
+	socketPath, cleanup := fakeSocket(t, func(conn net.Conn) {
+		header := make([]byte, i3IPCHeaderLength)
+		_, err := io.ReadFull(conn, header)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(i3IPCRunCommand), binary.LittleEndian.Uint32(header[len(i3IPCMagic)+4:]))
+		command := make([]byte, binary.LittleEndian.Uint32(header[len(i3IPCMagic):]))
+		_, err = io.ReadFull(conn, command)
+		require.NoError(t, err)
+		commands <- string(command)
+
+		reply := `[{"success":true}]`
+		if string(command) != "[con_id=5] kill" {
+			reply = `[{"success":false,"error":"No matching node."}]`
+		}
+		binary.LittleEndian.PutUint32(header[len(i3IPCMagic):], uint32(len(reply)))
+		_, err = conn.Write(append(header, reply...))
+		require.NoError(t, err)
+	})
+	defer cleanup()
+
+	require.NoError(t, swayCommand(socketPath, "[con_id=5] kill"))
+	assert.EqualError(
+		t,
+		swayCommand(socketPath, "[con_id=6] kill"),
+		`sway couldn't run "[con_id=6] kill": No matching node.`,
+	)
+	assert.Equal(t, "[con_id=5] kill", <-commands)
+	assert.Equal(t, "[con_id=6] kill", <-commands)
+}
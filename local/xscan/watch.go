@@ -0,0 +1,55 @@
+package xscan
+
+import (
+	"fmt"
+	"time"
+)
+
+// Emitted whenever focus moves to another window or the focused window changes its title.
+type FocusEvent struct {
+	Window Window
+	Time   time.Time
+}
+
+// Implemented by scanners that can push focus changes as they happen instead of being
+// polled. The returned channel is closed once done is closed.
+type Subscriber interface {
+	Subscribe(done <-chan struct{}) (<-chan FocusEvent, error)
+}
+
+// Returns a stream of focus events, starting with the currently focused window. Scanners
+// that implement Subscriber push events as they happen, all others are sampled every
+// pollInterval and an event is emitted when the sample changes.
+func Watch(scanner Scanner, pollInterval time.Duration, done <-chan struct{}) (<-chan FocusEvent, error) {
+	if subscriber, ok := scanner.(Subscriber); ok {
+		return subscriber.Subscribe(done)
+	}
+	events := make(chan FocusEvent)
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		first := true
+		lastWindow := Window{}
+		for {
+			window, err := scanner.CurrentWindow()
+			if err != nil {
+				fmt.Printf("Encountered an error with xscan %v\n", err)
+			} else if first || window != lastWindow {
+				select {
+				case events <- FocusEvent{Window: window, Time: time.Now()}:
+				case <-done:
+					return
+				}
+				first = false
+				lastWindow = window
+			}
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return events, nil
+}
@@ -0,0 +1,83 @@
+package xscan
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/xgb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeScanner struct {
+	lock    sync.Mutex
+	windows []Window
+}
+
+func (f *fakeScanner) CurrentWindow() (Window, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	window := f.windows[0]
+	if len(f.windows) > 1 {
+		f.windows = f.windows[1:]
+	}
+	return window, nil
+}
+
+func TestWatchPollsForChanges(t *testing.T) {
+	editor := Window{ApplicationName: "Code", Title: "manager.go"}
+	slack := Window{ApplicationName: "Slack", Title: "general | Team Slack"}
+	scanner := &fakeScanner{windows: []Window{editor, editor, slack, slack, editor}}
+
+	done := make(chan struct{})
+	defer close(done)
+	events, err := Watch(scanner, time.Millisecond, done)
+	require.NoError(t, err)
+
+	var windows []Window
+	lastTime := time.Time{}
+	for range []Window{editor, slack, editor} {
+		event := <-events
+		assert.False(t, event.Time.Before(lastTime))
+		lastTime = event.Time
+		windows = append(windows, event.Window)
+	}
+	assert.Equal(t, []Window{editor, slack, editor}, windows)
+}
+
+func TestX11Subscribe(t *testing.T) {
+	display, stop := startXvfb(t)
+	defer stop()
+
+	conn, err := xgb.NewConnDisplay(display)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	editor := createWindow(t, conn, "manager.go", "Code", 1)
+	setActiveWindow(t, conn, editor)
+
+	scanner, err := newX11Scanner(display)
+	require.NoError(t, err)
+	done := make(chan struct{})
+	defer close(done)
+	events, err := Watch(scanner, time.Hour, done)
+	require.NoError(t, err)
+
+	// The first event is the current window.
+	event := <-events
+	assert.Equal(t, "manager.go", event.Window.Title)
+
+	// Focus changes are pushed without waiting for the poll interval.
+	slack := createWindow(t, conn, "general | Team Slack", "Slack", 2)
+	setActiveWindow(t, conn, slack)
+	event = <-events
+	assert.Equal(t, "Slack", event.Window.ApplicationName)
+
+	// And so are title changes of the focused window.
+	utf8String, err := internAtom(conn, "UTF8_STRING")
+	require.NoError(t, err)
+	setProperty(t, conn, slack, "_NET_WM_NAME", utf8String, 8, []byte("random | Team Slack"))
+	event = <-events
+	assert.Equal(t, "random | Team Slack", event.Window.Title)
+}
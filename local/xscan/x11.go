@@ -0,0 +1,244 @@
+package xscan
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/xproto"
+)
+
+// Talks to the X server directly instead of shelling out to xdotool and xprop.
+type x11Scanner struct {
+	display string
+	conn    *xgb.Conn
+	root    xproto.Window
+	atoms   x11Atoms
+}
+
+type x11Atoms struct {
+	activeWindow xproto.Atom
+	wmName       xproto.Atom
+	netWMName    xproto.Atom
+	netWMPID     xproto.Atom
+	wmClass      xproto.Atom
+	utf8String   xproto.Atom
+}
+
+// Connects to the X server named by $DISPLAY.
+func NewX11() (Scanner, error) {
+	return newX11Scanner("")
+}
+
+func newX11Scanner(display string) (*x11Scanner, error) {
+	conn, err := xgb.NewConnDisplay(display)
+	if err != nil {
+		return nil, err
+	}
+	s := &x11Scanner{
+		display: display,
+		conn:    conn,
+		root:    xproto.Setup(conn).DefaultScreen(conn).Root,
+	}
+	for name, atom := range map[string]*xproto.Atom{
+		"_NET_ACTIVE_WINDOW": &s.atoms.activeWindow,
+		"WM_NAME":            &s.atoms.wmName,
+		"_NET_WM_NAME":       &s.atoms.netWMName,
+		"_NET_WM_PID":        &s.atoms.netWMPID,
+		"WM_CLASS":           &s.atoms.wmClass,
+		"UTF8_STRING":        &s.atoms.utf8String,
+	} {
+		*atom, err = internAtom(conn, name)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func internAtom(conn *xgb.Conn, name string) (xproto.Atom, error) {
+	reply, err := xproto.InternAtom(conn, false, uint16(len(name)), name).Reply()
+	if err != nil {
+		return 0, fmt.Errorf("unable to intern atom %s: %v", name, err)
+	}
+	return reply.Atom, nil
+}
+
+func (s *x11Scanner) CurrentWindow() (Window, error) {
+	id, err := s.activeWindow()
+	if err != nil {
+		return Window{}, err
+	}
+	if id == 0 {
+		// Nothing has focus, e.g. the desktop is showing.
+		return Window{}, nil
+	}
+	return s.window(id)
+}
+
+// Listens for PropertyNotify events on the root window to learn when _NET_ACTIVE_WINDOW
+// changes, and on the active window itself to learn when its title changes.
+func (s *x11Scanner) Subscribe(done <-chan struct{}) (<-chan FocusEvent, error) {
+	// Events are read on their own connection so CurrentWindow can still be used.
+	sub, err := newX11Scanner(s.display)
+	if err != nil {
+		return nil, err
+	}
+	err = sub.watchProperties(sub.root, true)
+	if err != nil {
+		sub.conn.Close()
+		return nil, err
+	}
+	events := make(chan FocusEvent)
+	go func() {
+		<-done
+		sub.conn.Close()
+	}()
+	go func() {
+		defer close(events)
+		activeID := xproto.Window(0)
+		lastWindow := Window{}
+		first := true
+		for {
+			id, err := sub.activeWindow()
+			if err != nil {
+				fmt.Printf("Encountered an error with xscan %v\n", err)
+			} else {
+				if id != activeID {
+					if activeID != 0 {
+						// The old window may already be gone, so there's nothing to do on error.
+						sub.watchProperties(activeID, false)
+					}
+					if id != 0 {
+						sub.watchProperties(id, true)
+					}
+					activeID = id
+				}
+				window := Window{}
+				if id != 0 {
+					window, err = sub.window(id)
+				}
+				if err != nil {
+					fmt.Printf("Encountered an error with xscan %v\n", err)
+				} else if first || window != lastWindow {
+					select {
+					case events <- FocusEvent{Window: window, Time: time.Now()}:
+					case <-done:
+						return
+					}
+					first = false
+					lastWindow = window
+				}
+			}
+			if !sub.waitForChange(activeID) {
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// Blocks until a property that might change the focused window or its title changes.
+// Returns false once the connection is closed.
+func (s *x11Scanner) waitForChange(activeID xproto.Window) bool {
+	for {
+		event, err := s.conn.WaitForEvent()
+		if event == nil && err == nil {
+			return false
+		}
+		if err != nil {
+			fmt.Printf("Encountered an error with xscan %v\n", err)
+			continue
+		}
+		notify, ok := event.(xproto.PropertyNotifyEvent)
+		if !ok {
+			continue
+		}
+		if notify.Window == s.root && notify.Atom == s.atoms.activeWindow {
+			return true
+		}
+		if notify.Window == activeID && (notify.Atom == s.atoms.netWMName || notify.Atom == s.atoms.wmName) {
+			return true
+		}
+	}
+}
+
+func (s *x11Scanner) watchProperties(id xproto.Window, watch bool) error {
+	mask := uint32(xproto.EventMaskNoEvent)
+	if watch {
+		mask = xproto.EventMaskPropertyChange
+	}
+	return xproto.ChangeWindowAttributesChecked(s.conn, id, xproto.CwEventMask, []uint32{mask}).Check()
+}
+
+func (s *x11Scanner) activeWindow() (xproto.Window, error) {
+	value, err := s.property(s.root, s.atoms.activeWindow, xproto.AtomWindow)
+	if err != nil {
+		return 0, err
+	}
+	if len(value) < 4 {
+		return 0, errors.New("_NET_ACTIVE_WINDOW is not set, is a window manager running?")
+	}
+	return xproto.Window(xgb.Get32(value)), nil
+}
+
+func (s *x11Scanner) window(id xproto.Window) (Window, error) {
+	title, err := s.title(id)
+	if err != nil {
+		return Window{}, err
+	}
+	pid := ""
+	value, err := s.property(id, s.atoms.netWMPID, xproto.AtomCardinal)
+	if err != nil {
+		return Window{}, err
+	}
+	if len(value) >= 4 {
+		pid = strconv.FormatUint(uint64(xgb.Get32(value)), 10)
+	}
+	class, err := s.property(id, s.atoms.wmClass, xproto.AtomString)
+	if err != nil {
+		return Window{}, err
+	}
+	return Window{
+		ApplicationName: applicationNameFromWMClass(class),
+		Title:           title,
+		PID:             pid,
+		WindowID:        strconv.FormatUint(uint64(id), 10),
+		Backend:         BackendX11,
+	}, nil
+}
+
+// Prefers the UTF-8 _NET_WM_NAME and falls back to the legacy WM_NAME.
+func (s *x11Scanner) title(id xproto.Window) (string, error) {
+	value, err := s.property(id, s.atoms.netWMName, s.atoms.utf8String)
+	if err != nil {
+		return "", err
+	}
+	if len(value) > 0 {
+		return string(value), nil
+	}
+	value, err = s.property(id, s.atoms.wmName, xproto.GetPropertyTypeAny)
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+func (s *x11Scanner) property(id xproto.Window, atom, kind xproto.Atom) ([]byte, error) {
+	reply, err := xproto.GetProperty(s.conn, false, id, atom, kind, 0, 1<<16).Reply()
+	if err != nil {
+		return nil, err
+	}
+	return reply.Value, nil
+}
+
+// WM_CLASS holds two null terminated strings, the instance name and the class name.
+// Match what we used to pull out of xprop and use the class name.
+func applicationNameFromWMClass(value []byte) string {
+	parts := bytes.Split(bytes.TrimRight(value, "\x00"), []byte{0})
+	return string(parts[len(parts)-1])
+}
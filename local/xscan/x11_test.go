@@ -0,0 +1,121 @@
+package xscan
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Starts a throwaway Xvfb server and returns its display name.
+func startXvfb(t *testing.T) (string, func()) {
+	path, err := exec.LookPath("Xvfb")
+	if err != nil {
+		t.Skip("Xvfb is not installed")
+	}
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+
+	// Xvfb picks a free display and writes its number to fd 3.
+	cmd := exec.Command(path, "-displayfd", "3", "-nolisten", "tcp")
+	cmd.ExtraFiles = []*os.File{w}
+	require.NoError(t, cmd.Start())
+	w.Close()
+
+	display, err := bufio.NewReader(r).ReadString('\n')
+	require.NoError(t, err)
+	return ":" + strings.TrimSpace(display), func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}
+}
+
+func setProperty(t *testing.T, conn *xgb.Conn, id xproto.Window, name string, kind xproto.Atom, format byte, value []byte) {
+	atom, err := internAtom(conn, name)
+	require.NoError(t, err)
+	err = xproto.ChangePropertyChecked(
+		conn, xproto.PropModeReplace, id, atom, kind, format, uint32(len(value))/uint32(format/8), value,
+	).Check()
+	require.NoError(t, err)
+}
+
+func createWindow(t *testing.T, conn *xgb.Conn, title, class string, pid uint32) xproto.Window {
+	screen := xproto.Setup(conn).DefaultScreen(conn)
+	id, err := xproto.NewWindowId(conn)
+	require.NoError(t, err)
+	err = xproto.CreateWindowChecked(
+		conn, screen.RootDepth, id, screen.Root, 0, 0, 100, 100, 0,
+		xproto.WindowClassInputOutput, screen.RootVisual, 0, nil,
+	).Check()
+	require.NoError(t, err)
+
+	utf8String, err := internAtom(conn, "UTF8_STRING")
+	require.NoError(t, err)
+	setProperty(t, conn, id, "_NET_WM_NAME", utf8String, 8, []byte(title))
+	setProperty(t, conn, id, "WM_CLASS", xproto.AtomString, 8, []byte(strings.ToLower(class)+"\x00"+class+"\x00"))
+	pidValue := make([]byte, 4)
+	xgb.Put32(pidValue, pid)
+	setProperty(t, conn, id, "_NET_WM_PID", xproto.AtomCardinal, 32, pidValue)
+	return id
+}
+
+func setActiveWindow(t *testing.T, conn *xgb.Conn, id xproto.Window) {
+	value := make([]byte, 4)
+	xgb.Put32(value, uint32(id))
+	root := xproto.Setup(conn).DefaultScreen(conn).Root
+	setProperty(t, conn, root, "_NET_ACTIVE_WINDOW", xproto.AtomWindow, 32, value)
+}
+
+func TestX11CurrentWindow(t *testing.T) {
+	display, stop := startXvfb(t)
+	defer stop()
+
+	// Act as the window manager on a separate connection.
+	conn, err := xgb.NewConnDisplay(display)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	scanner, err := newX11Scanner(display)
+	require.NoError(t, err)
+
+	// Without a window manager there is no active window at all.
+	_, err = scanner.CurrentWindow()
+	assert.Error(t, err)
+
+	setActiveWindow(t, conn, 0)
+	window, err := scanner.CurrentWindow()
+	require.NoError(t, err)
+	assert.Equal(t, Window{}, window)
+
+	slack := createWindow(t, conn, "general | Team Slack", "Slack", 1234)
+	setActiveWindow(t, conn, slack)
+	window, err = scanner.CurrentWindow()
+	require.NoError(t, err)
+	assert.Equal(t, "Slack", window.ApplicationName)
+	assert.Equal(t, "general | Team Slack", window.Title)
+	assert.Equal(t, "1234", window.PID)
+	assert.NotEmpty(t, window.WindowID)
+	assert.Equal(t, BackendX11, window.Backend)
+
+	// Titles should be read as UTF-8.
+	editor := createWindow(t, conn, "manager.go — glider", "Code", 42)
+	setActiveWindow(t, conn, editor)
+	window, err = scanner.CurrentWindow()
+	require.NoError(t, err)
+	assert.Equal(t, "Code", window.ApplicationName)
+	assert.Equal(t, "manager.go — glider", window.Title)
+	assert.Equal(t, "42", window.PID)
+}
+
+func TestApplicationNameFromWMClass(t *testing.T) {
+	assert.Equal(t, "Slack", applicationNameFromWMClass([]byte("slack\x00Slack\x00")))
+	assert.Equal(t, "Slack", applicationNameFromWMClass([]byte("Slack")))
+	assert.Equal(t, "", applicationNameFromWMClass(nil))
+}
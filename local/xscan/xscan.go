@@ -3,6 +3,8 @@ package xscan
 import (
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 
@@ -15,16 +17,77 @@ type Scanner interface {
 }
 
 type Window struct {
-	ApplicationName string
-	Title           string
-	PID             string
-	WindowID        string
+	ApplicationName string `json:"application,omitempty"`
+	Title           string `json:"title,omitempty"`
+	PID             string `json:"pid,omitempty"`
+	WindowID        string `json:"window_id,omitempty"`
+	// The backend that found the window, which WindowID only means something to.
+	Backend string `json:"backend,omitempty"`
+	// The site a browser window is showing, if it's known. Scanners leave this empty,
+	// see the browser package.
+	Domain string `json:"domain,omitempty"`
+	// The project a terminal or editor window is working on, if it's known. Scanners
+	// leave this empty too, see the project package.
+	Project string `json:"project,omitempty"`
 }
 
+// Stands in for the focused window while the user is away from the keyboard.
+var IdleWindow = Window{ApplicationName: "idle"}
+
+// Names of the scanner backends that can be selected at startup.
+const (
+	BackendAuto     = "auto"
+	BackendXdotool  = "xdotool"
+	BackendX11      = "x11"
+	BackendSway     = "sway"
+	BackendHyprland = "hyprland"
+)
+
+// Shells out to xdotool and xprop to find the focused window.
 func New() Scanner {
 	return scannerImpl{}
 }
 
+// Returns the scanner for the named backend, or for the detected one if name is "auto".
+func NewBackend(name string) (Scanner, error) {
+	if name == BackendAuto {
+		detected, err := DetectBackend()
+		if err != nil {
+			return nil, err
+		}
+		name = detected
+	}
+	switch name {
+	case BackendXdotool:
+		return New(), nil
+	case BackendX11:
+		return NewX11()
+	case BackendSway:
+		return NewSway()
+	case BackendHyprland:
+		return NewHyprland()
+	default:
+		return nil, fmt.Errorf("unknown scanner backend %q", name)
+	}
+}
+
+// Picks a backend based on the environment of the current session. Wayland compositors
+// are checked first since they usually also run XWayland and set $DISPLAY.
+func DetectBackend() (string, error) {
+	switch {
+	case os.Getenv("HYPRLAND_INSTANCE_SIGNATURE") != "":
+		return BackendHyprland, nil
+	case os.Getenv("SWAYSOCK") != "":
+		return BackendSway, nil
+	case os.Getenv("WAYLAND_DISPLAY") != "":
+		return "", errors.New("unsupported wayland compositor, only sway and Hyprland are supported")
+	case os.Getenv("DISPLAY") != "":
+		return BackendX11, nil
+	default:
+		return "", errors.New("unable to detect a scanner backend, neither X11 nor wayland seem to be running")
+	}
+}
+
 type scannerImpl struct{}
 
 func (scannerImpl) CurrentWindow() (Window, error) {
@@ -52,9 +115,22 @@ func (scannerImpl) CurrentWindow() (Window, error) {
 		Title:           title,
 		PID:             pid,
 		WindowID:        windowID,
+		Backend:         BackendXdotool,
 	}, nil
 }
 
+// Returns the base name of the executable running as pid, or "" if it can't be found.
+func Executable(pid string) string {
+	if pid == "" {
+		return ""
+	}
+	path, err := os.Readlink(filepath.Join("/proc", pid, "exe"))
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(path)
+}
+
 var nameRegex = regexp.MustCompile(`WM_CLASS.*"(?P<Name>\S*)"$`)
 
 func applicationNameFromXProps(xprops string) string {
@@ -0,0 +1,58 @@
+package xscan
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Sets an environment variable and returns a function that restores its old value.
+func setenv(t *testing.T, name, value string) func() {
+	old, ok := os.LookupEnv(name)
+	require.NoError(t, os.Setenv(name, value))
+	return func() {
+		if ok {
+			os.Setenv(name, old)
+		} else {
+			os.Unsetenv(name)
+		}
+	}
+}
+
+func TestDetectBackend(t *testing.T) {
+	for _, name := range []string{"HYPRLAND_INSTANCE_SIGNATURE", "SWAYSOCK", "WAYLAND_DISPLAY", "DISPLAY"} {
+		defer setenv(t, name, "")()
+	}
+	_, err := DetectBackend()
+	assert.Error(t, err)
+
+	os.Setenv("DISPLAY", ":0")
+	backend, err := DetectBackend()
+	require.NoError(t, err)
+	assert.Equal(t, BackendX11, backend)
+
+	// Wayland compositors win over XWayland.
+	os.Setenv("WAYLAND_DISPLAY", "wayland-1")
+	_, err = DetectBackend()
+	assert.Error(t, err)
+
+	os.Setenv("SWAYSOCK", "/run/user/1000/sway-ipc.sock")
+	backend, err = DetectBackend()
+	require.NoError(t, err)
+	assert.Equal(t, BackendSway, backend)
+
+	os.Setenv("HYPRLAND_INSTANCE_SIGNATURE", "abc")
+	backend, err = DetectBackend()
+	require.NoError(t, err)
+	assert.Equal(t, BackendHyprland, backend)
+}
+
+func TestApplicationNameFromXProps(t *testing.T) {
+	xprops := "_NET_WM_PID(CARDINAL) = 1234\n" +
+		"WM_CLASS(STRING) = \"slack\", \"Slack\"\n" +
+		"WM_NAME(STRING) = \"general | Team Slack\"\n"
+	assert.Equal(t, "Slack", applicationNameFromXProps(xprops))
+	assert.Equal(t, "", applicationNameFromXProps("WM_NAME(STRING) = \"foo\""))
+}